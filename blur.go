@@ -0,0 +1,200 @@
+package Netpbm
+
+import "math"
+
+// boxBlurSigma is the standard deviation above which Gaussian blurs are
+// approximated by repeated box blurs, whose cost does not grow with sigma.
+const boxBlurSigma = 4
+
+// boxSizes returns the widths of n box blurs whose succession approximates a
+// Gaussian blur of standard deviation sigma.
+func boxSizes(sigma float64, n int) []int {
+	ideal := math.Sqrt(12*sigma*sigma/float64(n) + 1)
+	lower := int(ideal)
+	if lower%2 == 0 {
+		lower--
+	}
+	upper := lower + 2
+	m := int(math.Round((12*sigma*sigma - float64(n*lower*lower) - float64(4*n*lower) - float64(3*n)) / float64(-4*lower-4)))
+	sizes := make([]int, n)
+	for i := range sizes {
+		if i < m {
+			sizes[i] = lower
+		} else {
+			sizes[i] = upper
+		}
+	}
+	return sizes
+}
+
+// boxBlur returns p averaged over a square window of the given radius with
+// running sums, repeating edge pixels past the borders.
+func (p *plane) boxBlur(radius int) *plane {
+	n := float64(2*radius + 1)
+	tmp := newPlane(p.width, p.height)
+	for y := 0; y < p.height; y++ {
+		sum := 0.0
+		for i := -radius; i <= radius; i++ {
+			sum += p.at(clamp(i, 0, p.width-1), y)
+		}
+		for x := 0; x < p.width; x++ {
+			tmp.set(x, y, sum/n)
+			sum += p.at(clamp(x+radius+1, 0, p.width-1), y) - p.at(clamp(x-radius, 0, p.width-1), y)
+		}
+	}
+	out := newPlane(p.width, p.height)
+	for x := 0; x < p.width; x++ {
+		sum := 0.0
+		for i := -radius; i <= radius; i++ {
+			sum += tmp.at(x, clamp(i, 0, p.height-1))
+		}
+		for y := 0; y < p.height; y++ {
+			out.set(x, y, sum/n)
+			sum += tmp.at(x, clamp(y+radius+1, 0, p.height-1)) - tmp.at(x, clamp(y-radius, 0, p.height-1))
+		}
+	}
+	return out
+}
+
+// gaussianBlur returns p blurred with a Gaussian of standard deviation
+// sigma. Small sigmas use the exact kernel and large ones three box blurs.
+func (p *plane) gaussianBlur(sigma float64) *plane {
+	if sigma <= 0 {
+		return p
+	}
+	if sigma <= boxBlurSigma {
+		weights := gaussianWeights(sigma)
+		return p.separable(weights, weights, EdgeClamp)
+	}
+	for _, size := range boxSizes(sigma, 3) {
+		p = p.boxBlur(size / 2)
+	}
+	return p
+}
+
+// unsharpMask returns p sharpened by adding amount times its difference with
+// a Gaussian blur of standard deviation radius. Differences smaller than
+// threshold, in normalized levels, are left alone so noise is not amplified.
+func (p *plane) unsharpMask(radius, amount, threshold float64) *plane {
+	blurred := p.gaussianBlur(radius)
+	out := newPlane(p.width, p.height)
+	for i, v := range p.pix {
+		d := v - blurred.pix[i]
+		if math.Abs(d) < threshold {
+			d = 0
+		}
+		out.pix[i] = v + amount*d
+	}
+	return out
+}
+
+// bilateral returns the planes ps smoothed with a bilateral filter: every
+// pixel becomes the mean of its neighbors weighted by a Gaussian of their
+// distance, of standard deviation sigmaSpace, and a Gaussian of their color
+// difference across all planes, of standard deviation sigmaRange.
+func bilateral(ps []*plane, sigmaSpace, sigmaRange float64) []*plane {
+	width, height := ps[0].width, ps[0].height
+	radius := int(math.Ceil(3 * sigmaSpace))
+	size := 2*radius + 1
+	spatial := make([]float64, size*size)
+	for dy := -radius; dy <= radius; dy++ {
+		for dx := -radius; dx <= radius; dx++ {
+			d2 := float64(dx*dx + dy*dy)
+			spatial[(dy+radius)*size+dx+radius] = math.Exp(-d2 / (2 * sigmaSpace * sigmaSpace))
+		}
+	}
+	rangeScale := -1 / (2 * sigmaRange * sigmaRange)
+
+	out := make([]*plane, len(ps))
+	for c := range out {
+		out[c] = newPlane(width, height)
+	}
+	sums := make([]float64, len(ps))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			for c := range sums {
+				sums[c] = 0
+			}
+			total := 0.0
+			for sy := max(y-radius, 0); sy <= min(y+radius, height-1); sy++ {
+				for sx := max(x-radius, 0); sx <= min(x+radius, width-1); sx++ {
+					d2 := 0.0
+					for _, p := range ps {
+						d := p.at(sx, sy) - p.at(x, y)
+						d2 += d * d
+					}
+					w := spatial[(sy-y+radius)*size+sx-x+radius] * math.Exp(d2*rangeScale)
+					for c, p := range ps {
+						sums[c] += w * p.at(sx, sy)
+					}
+					total += w
+				}
+			}
+			for c := range out {
+				out[c].set(x, y, sums[c]/total)
+			}
+		}
+	}
+	return out
+}
+
+// GaussianBlur blurs the PGM image with a Gaussian of standard deviation
+// sigma, in pixels. Sigmas above four are approximated by three box blurs,
+// so large blurs stay fast.
+func (pgm *PGM) GaussianBlur(sigma float64) {
+	pgm.setPlane(pgm.plane(false).gaussianBlur(sigma), false)
+}
+
+// UnsharpMask sharpens the PGM image by adding amount times the difference
+// between the image and a Gaussian blur of standard deviation radius.
+// Differences below threshold, in samples, are ignored so flat areas and
+// noise are kept as they are.
+func (pgm *PGM) UnsharpMask(radius, amount float64, threshold uint8) {
+	t := float64(threshold) / math.Max(float64(pgm.max), 1)
+	pgm.setPlane(pgm.plane(false).unsharpMask(radius, amount, t), false)
+}
+
+// Bilateral smooths the PGM image while keeping edges: neighbors are
+// weighted by their distance, with standard deviation sigmaSpace in pixels,
+// and by their difference in level, with standard deviation sigmaRange as a
+// fraction of the max value. The cost grows with the square of sigmaSpace.
+func (pgm *PGM) Bilateral(sigmaSpace, sigmaRange float64) {
+	if sigmaSpace <= 0 || sigmaRange <= 0 {
+		return
+	}
+	out := bilateral([]*plane{pgm.plane(false)}, sigmaSpace, sigmaRange)
+	pgm.setPlane(out[0], false)
+}
+
+// GaussianBlur blurs every channel of the PPM image with a Gaussian of
+// standard deviation sigma, in pixels.
+func (ppm *PPM) GaussianBlur(sigma float64) {
+	ps := ppm.planes(false)
+	for c := range ps {
+		ps[c] = ps[c].gaussianBlur(sigma)
+	}
+	ppm.setPlanes(ps, false)
+}
+
+// UnsharpMask sharpens every channel of the PPM image with the same
+// parameters as PGM.UnsharpMask.
+func (ppm *PPM) UnsharpMask(radius, amount float64, threshold uint8) {
+	t := float64(threshold) / math.Max(float64(ppm.max), 1)
+	ps := ppm.planes(false)
+	for c := range ps {
+		ps[c] = ps[c].unsharpMask(radius, amount, t)
+	}
+	ppm.setPlanes(ps, false)
+}
+
+// Bilateral smooths the PPM image while keeping edges, like PGM.Bilateral.
+// Color differences are measured across all three channels, so edges between
+// colors of similar brightness are kept too.
+func (ppm *PPM) Bilateral(sigmaSpace, sigmaRange float64) {
+	if sigmaSpace <= 0 || sigmaRange <= 0 {
+		return
+	}
+	ps := ppm.planes(false)
+	out := bilateral(ps[:], sigmaSpace, sigmaRange)
+	ppm.setPlanes([3]*plane{out[0], out[1], out[2]}, false)
+}
@@ -0,0 +1,102 @@
+package Netpbm
+
+import "fmt"
+
+// set stores v as the sample of channel c in p.
+func (c Channel) set(p *Pixel, v uint8) {
+	switch c {
+	case Green:
+		p.G = v
+	case Blue:
+		p.B = v
+	default:
+		p.R = v
+	}
+}
+
+// grayMagic returns the PGM magic number matching the encoding of a PPM
+// magic number: binary P6 becomes P5 and anything else P2.
+func grayMagic(magicNumber string) string {
+	if magicNumber == "P6" {
+		return "P5"
+	}
+	return "P2"
+}
+
+// ExtractChannel returns a PGM image holding channel c of the PPM image,
+// like pamchannel. The max value is kept.
+func (ppm *PPM) ExtractChannel(c Channel) *PGM {
+	pgm := &PGM{newGrid[uint8](ppm.width, ppm.height), ppm.width, ppm.height, grayMagic(ppm.magicNumber), ppm.max}
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			pgm.data[y][x] = c.of(ppm.data[y][x])
+		}
+	}
+	return pgm
+}
+
+// SplitChannels returns the red, green and blue channels of the PPM image
+// as three PGM images.
+func (ppm *PPM) SplitChannels() (r, g, b *PGM) {
+	return ppm.ExtractChannel(Red), ppm.ExtractChannel(Green), ppm.ExtractChannel(Blue)
+}
+
+// MergeChannels builds a PPM image from red, green and blue PGM images, like
+// rgb3toppm. The images must have the same size and max value.
+func MergeChannels(r, g, b *PGM) (*PPM, error) {
+	for _, pgm := range []*PGM{g, b} {
+		if pgm.width != r.width || pgm.height != r.height {
+			return nil, fmt.Errorf("channel size mismatch: %dx%d and %dx%d", r.width, r.height, pgm.width, pgm.height)
+		}
+		if pgm.max != r.max {
+			return nil, fmt.Errorf("channel max value mismatch: %d and %d", r.max, pgm.max)
+		}
+	}
+	ppm := &PPM{newGrid[Pixel](r.width, r.height), r.width, r.height, colorMagic(r.magicNumber), r.max}
+	for y := 0; y < r.height; y++ {
+		for x := 0; x < r.width; x++ {
+			ppm.data[y][x] = Pixel{r.data[y][x], g.data[y][x], b.data[y][x]}
+		}
+	}
+	return ppm, nil
+}
+
+// SetChannel replaces channel c of the PPM image with the samples of pgm,
+// which must have the same size and max value.
+func (ppm *PPM) SetChannel(c Channel, pgm *PGM) error {
+	if pgm.width != ppm.width || pgm.height != ppm.height {
+		return fmt.Errorf("channel size mismatch: %dx%d and %dx%d", ppm.width, ppm.height, pgm.width, pgm.height)
+	}
+	if pgm.max != ppm.max {
+		return fmt.Errorf("channel max value mismatch: %d and %d", ppm.max, pgm.max)
+	}
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			c.set(&ppm.data[y][x], pgm.data[y][x])
+		}
+	}
+	return nil
+}
+
+// ReorderChannels rearranges the channels of the PPM image so that the new
+// red, green and blue channels are taken from the old channels r, g and b.
+// ReorderChannels(Blue, Green, Red) converts between RGB and BGR.
+func (ppm *PPM) ReorderChannels(r, g, b Channel) {
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			p := ppm.data[y][x]
+			ppm.data[y][x] = Pixel{r.of(p), g.of(p), b.of(p)}
+		}
+	}
+}
+
+// ApplyToChannel runs op on channel c of the PPM image, extracted as a PGM
+// image, and stores the result back. Any PGM operation that keeps the size
+// and max value can be used, for example:
+//
+//	ppm.ApplyToChannel(Blue, (*PGM).Invert)
+func (ppm *PPM) ApplyToChannel(c Channel, op func(*PGM)) error {
+	pgm := ppm.ExtractChannel(c)
+	op(pgm)
+	return ppm.SetChannel(c, pgm)
+}
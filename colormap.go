@@ -0,0 +1,150 @@
+package Netpbm
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// ColorStop is a color placed at a position in [0, 1] along a Colormap.
+// Colors are 8-bit samples with a max value of 255.
+type ColorStop struct {
+	Pos   float64
+	Color Pixel
+}
+
+// Colormap maps normalized gray levels to colors by interpolating linearly
+// between its stops.
+type Colormap struct {
+	stops []ColorStop
+}
+
+// NewColormap returns a colormap built from at least two stops. The stops
+// may be given in any order but their positions must lie in [0, 1]. Levels
+// before the first stop or after the last one take the color of that stop.
+func NewColormap(stops ...ColorStop) (*Colormap, error) {
+	if len(stops) < 2 {
+		return nil, errors.New("a colormap needs at least two stops")
+	}
+	sorted := append([]ColorStop(nil), stops...)
+	for _, s := range sorted {
+		if !(s.Pos >= 0 && s.Pos <= 1) {
+			return nil, errors.New("colormap stop position out of range [0, 1]")
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Pos < sorted[j].Pos })
+	return &Colormap{sorted}, nil
+}
+
+// evenStops places colors at evenly spaced positions from 0 to 1.
+func evenStops(colors ...Pixel) *Colormap {
+	stops := make([]ColorStop, len(colors))
+	for i, c := range colors {
+		stops[i] = ColorStop{float64(i) / float64(len(colors)-1), c}
+	}
+	return &Colormap{stops}
+}
+
+// Built-in colormaps. Viridis, Magma, Inferno and Plasma are the perceptually
+// uniform maps from matplotlib and Turbo is Google's improved rainbow map,
+// all sampled at eleven points of their published 256-entry tables. Jet is
+// the classic MATLAB rainbow.
+var (
+	Viridis = evenStops(
+		Pixel{68, 1, 84}, Pixel{72, 36, 117}, Pixel{65, 68, 135}, Pixel{53, 95, 141},
+		Pixel{42, 120, 142}, Pixel{33, 145, 140}, Pixel{34, 168, 132}, Pixel{68, 191, 112},
+		Pixel{122, 209, 81}, Pixel{189, 223, 38}, Pixel{253, 231, 37},
+	)
+	Magma = evenStops(
+		Pixel{0, 0, 4}, Pixel{20, 14, 54}, Pixel{59, 15, 112}, Pixel{100, 26, 128},
+		Pixel{140, 41, 129}, Pixel{183, 55, 121}, Pixel{222, 73, 104}, Pixel{247, 112, 92},
+		Pixel{254, 159, 109}, Pixel{254, 207, 146}, Pixel{252, 253, 191},
+	)
+	Inferno = evenStops(
+		Pixel{0, 0, 4}, Pixel{22, 11, 57}, Pixel{66, 10, 104}, Pixel{106, 23, 110},
+		Pixel{147, 38, 103}, Pixel{188, 55, 84}, Pixel{221, 81, 58}, Pixel{243, 120, 25},
+		Pixel{252, 165, 10}, Pixel{246, 215, 70}, Pixel{252, 255, 164},
+	)
+	Plasma = evenStops(
+		Pixel{13, 8, 135}, Pixel{65, 4, 157}, Pixel{106, 0, 168}, Pixel{143, 13, 164},
+		Pixel{177, 42, 144}, Pixel{204, 71, 120}, Pixel{225, 100, 98}, Pixel{242, 132, 75},
+		Pixel{252, 166, 54}, Pixel{252, 206, 37}, Pixel{240, 249, 33},
+	)
+	Turbo = evenStops(
+		Pixel{48, 18, 59}, Pixel{69, 92, 207}, Pixel{62, 155, 254}, Pixel{24, 215, 202},
+		Pixel{70, 248, 132}, Pixel{164, 252, 60}, Pixel{225, 221, 55}, Pixel{254, 164, 49},
+		Pixel{240, 91, 18}, Pixel{195, 37, 3}, Pixel{122, 4, 3},
+	)
+	Grayscale = evenStops(Pixel{0, 0, 0}, Pixel{255, 255, 255})
+	Jet       = &Colormap{[]ColorStop{
+		{0, Pixel{0, 0, 128}},
+		{0.125, Pixel{0, 0, 255}},
+		{0.375, Pixel{0, 255, 255}},
+		{0.625, Pixel{255, 255, 0}},
+		{0.875, Pixel{255, 0, 0}},
+		{1, Pixel{128, 0, 0}},
+	}}
+)
+
+// At returns the color of the colormap at position t in [0, 1]. A nil or
+// zero Colormap, which has no stops, gives black everywhere.
+func (cm *Colormap) At(t float64) Pixel {
+	if cm == nil || len(cm.stops) == 0 {
+		return Pixel{}
+	}
+	stops := cm.stops
+	if !(t > stops[0].Pos) {
+		return stops[0].Color
+	}
+	last := stops[len(stops)-1]
+	if t >= last.Pos {
+		return last.Color
+	}
+	i := sort.Search(len(stops), func(i int) bool { return stops[i].Pos > t })
+	a, b := stops[i-1], stops[i]
+	f := (t - a.Pos) / (b.Pos - a.Pos)
+	mix := func(u, v uint8) uint8 {
+		return uint8(math.Round(float64(u) + f*(float64(v)-float64(u))))
+	}
+	return Pixel{mix(a.Color.R, b.Color.R), mix(a.Color.G, b.Color.G), mix(a.Color.B, b.Color.B)}
+}
+
+// colorMagic returns the PPM magic number matching the encoding of a PGM
+// magic number: binary P5 becomes P6 and anything else P3.
+func colorMagic(magicNumber string) string {
+	if magicNumber == "P5" {
+		return "P6"
+	}
+	return "P3"
+}
+
+// ToPPM converts the PGM image to a PPM image without loss by copying every
+// gray level into the red, green and blue channels. The max value is kept.
+func (pgm *PGM) ToPPM() *PPM {
+	ppm := &PPM{newGrid[Pixel](pgm.width, pgm.height), pgm.width, pgm.height, colorMagic(pgm.magicNumber), pgm.max}
+	for y := 0; y < pgm.height; y++ {
+		for x := 0; x < pgm.width; x++ {
+			v := pgm.data[y][x]
+			ppm.data[y][x] = Pixel{v, v, v}
+		}
+	}
+	return ppm
+}
+
+// ToPPMWith renders the PGM image in false color: every gray level is scaled
+// to [0, 1] by the max value and looked up in cm. The result has a max value
+// of 255.
+func (pgm *PGM) ToPPMWith(cm *Colormap) *PPM {
+	ppm := &PPM{newGrid[Pixel](pgm.width, pgm.height), pgm.width, pgm.height, colorMagic(pgm.magicNumber), 255}
+	table := decodeTable(pgm.max, false)
+	colors := make([]Pixel, len(table))
+	for v := range colors {
+		colors[v] = cm.At(table[v])
+	}
+	for y := 0; y < pgm.height; y++ {
+		for x := 0; x < pgm.width; x++ {
+			ppm.data[y][x] = colors[pgm.data[y][x]]
+		}
+	}
+	return ppm
+}
@@ -0,0 +1,59 @@
+package Netpbm
+
+import "testing"
+
+func TestTurboStops(t *testing.T) {
+	// Entries 0, 128 and 255 of the published Turbo table, not of its
+	// polynomial approximation.
+	tests := []struct {
+		t    float64
+		want Pixel
+	}{
+		{0, Pixel{48, 18, 59}},
+		{0.5, Pixel{164, 252, 60}},
+		{1, Pixel{122, 4, 3}},
+	}
+	for _, tt := range tests {
+		if got := Turbo.At(tt.t); got != tt.want {
+			t.Errorf("Turbo.At(%g) = %v, want %v", tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestColormapWithoutStops(t *testing.T) {
+	pgm := flatPGM(3, 2, 100, 255)
+	for _, cm := range []*Colormap{nil, {}} {
+		if got := cm.At(0.5); got != (Pixel{}) {
+			t.Errorf("At on a colormap without stops = %v, want black", got)
+		}
+		ppm := pgm.ToPPMWith(cm)
+		for y := range ppm.data {
+			for x, p := range ppm.data[y] {
+				if p != (Pixel{}) {
+					t.Fatalf("ToPPMWith without stops: pixel (%d, %d) is %v, want black", x, y, p)
+				}
+			}
+		}
+	}
+}
+
+func TestNewColormapValidates(t *testing.T) {
+	invalid := [][]ColorStop{
+		nil,
+		{{0.5, Pixel{1, 2, 3}}},
+		{{0, Pixel{}}, {1.5, Pixel{}}},
+		{{-0.1, Pixel{}}, {1, Pixel{}}},
+	}
+	for i, stops := range invalid {
+		if _, err := NewColormap(stops...); err == nil {
+			t.Errorf("NewColormap accepted stops %d", i)
+		}
+	}
+	cm, err := NewColormap(ColorStop{1, Pixel{200, 0, 0}}, ColorStop{0, Pixel{0, 0, 100}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cm.At(0.5); got != (Pixel{100, 0, 50}) {
+		t.Errorf("unsorted stops: At(0.5) = %v, want {100 0 50}", got)
+	}
+}
@@ -0,0 +1,307 @@
+package Netpbm
+
+import "math"
+
+// HSV is a color in the hue, saturation, value model. H is in degrees in
+// [0, 360) and S and V are in [0, 1].
+type HSV struct {
+	H, S, V float64
+}
+
+// HSL is a color in the hue, saturation, lightness model. H is in degrees in
+// [0, 360) and S and L are in [0, 1].
+type HSL struct {
+	H, S, L float64
+}
+
+// YCbCr is a color split into luma and two color differences. Y is in
+// [0, 1] and Cb and Cr are in [-0.5, 0.5]; no headroom or footroom is used.
+type YCbCr struct {
+	Y, Cb, Cr float64
+}
+
+// YCbCrStandard selects the luma coefficients of a YCbCr conversion.
+type YCbCrStandard int
+
+const (
+	BT601 YCbCrStandard = iota
+	BT709
+)
+
+// LinearRGB is a color with the sRGB transfer curve removed. Components are
+// in [0, 1].
+type LinearRGB struct {
+	R, G, B float64
+}
+
+// XYZ is a CIE 1931 XYZ color relative to the D65 white point, scaled so
+// that white has Y = 1.
+type XYZ struct {
+	X, Y, Z float64
+}
+
+// Lab is a CIE L*a*b* color relative to the D65 white point. L is in
+// [0, 100]; A and B are roughly in [-128, 127].
+type Lab struct {
+	L, A, B float64
+}
+
+// D65 reference white in XYZ.
+const (
+	whiteX = 0.95047
+	whiteY = 1.0
+	whiteZ = 1.08883
+)
+
+// normalized returns the samples of p divided by max.
+func (p Pixel) normalized(max uint8) (float64, float64, float64) {
+	if max == 0 {
+		return 0, 0, 0
+	}
+	m := float64(max)
+	return float64(p.R) / m, float64(p.G) / m, float64(p.B) / m
+}
+
+// pixelOf builds a pixel from normalized components, rounding and clamping
+// them to [0, max].
+func pixelOf(r, g, b float64, max uint8) Pixel {
+	return Pixel{toSample(r, max), toSample(g, max), toSample(b, max)}
+}
+
+// hueOf returns the hue in degrees of normalized components whose largest
+// value is hi and spread is delta.
+func hueOf(r, g, b, hi, delta float64) float64 {
+	if delta == 0 {
+		return 0
+	}
+	var h float64
+	switch hi {
+	case r:
+		h = math.Mod((g-b)/delta, 6)
+	case g:
+		h = (b-r)/delta + 2
+	default:
+		h = (r-g)/delta + 4
+	}
+	h *= 60
+	if h < 0 {
+		h += 360
+	}
+	return h
+}
+
+// hueToRGB returns the normalized components of a color with hue h,
+// chroma c and smallest component m.
+func hueToRGB(h, c, m float64) (float64, float64, float64) {
+	h = math.Mod(h, 360)
+	if h < 0 {
+		h += 360
+	}
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	var r, g, b float64
+	switch {
+	case h < 60:
+		r, g, b = c, x, 0
+	case h < 120:
+		r, g, b = x, c, 0
+	case h < 180:
+		r, g, b = 0, c, x
+	case h < 240:
+		r, g, b = 0, x, c
+	case h < 300:
+		r, g, b = x, 0, c
+	default:
+		r, g, b = c, 0, x
+	}
+	return r + m, g + m, b + m
+}
+
+// HSV converts the pixel, whose samples range up to max, to HSV.
+func (p Pixel) HSV(max uint8) HSV {
+	r, g, b := p.normalized(max)
+	hi, lo := math.Max(r, math.Max(g, b)), math.Min(r, math.Min(g, b))
+	s := 0.0
+	if hi > 0 {
+		s = (hi - lo) / hi
+	}
+	return HSV{hueOf(r, g, b, hi, hi-lo), s, hi}
+}
+
+// Pixel converts the color to a pixel with samples ranging up to max.
+func (c HSV) Pixel(max uint8) Pixel {
+	chroma := c.V * c.S
+	r, g, b := hueToRGB(c.H, chroma, c.V-chroma)
+	return pixelOf(r, g, b, max)
+}
+
+// HSL converts the pixel, whose samples range up to max, to HSL.
+func (p Pixel) HSL(max uint8) HSL {
+	r, g, b := p.normalized(max)
+	hi, lo := math.Max(r, math.Max(g, b)), math.Min(r, math.Min(g, b))
+	l := (hi + lo) / 2
+	s := 0.0
+	if hi != lo {
+		s = (hi - lo) / (1 - math.Abs(2*l-1))
+	}
+	return HSL{hueOf(r, g, b, hi, hi-lo), s, l}
+}
+
+// Pixel converts the color to a pixel with samples ranging up to max.
+func (c HSL) Pixel(max uint8) Pixel {
+	chroma := (1 - math.Abs(2*c.L-1)) * c.S
+	r, g, b := hueToRGB(c.H, chroma, c.L-chroma/2)
+	return pixelOf(r, g, b, max)
+}
+
+// lumaWeights returns the red and blue luma coefficients of std; green takes
+// the rest.
+func (std YCbCrStandard) lumaWeights() (float64, float64) {
+	if std == BT709 {
+		return 0.2126, 0.0722
+	}
+	return 0.299, 0.114
+}
+
+// YCbCr converts the pixel, whose samples range up to max, to YCbCr with the
+// coefficients of std.
+func (p Pixel) YCbCr(max uint8, std YCbCrStandard) YCbCr {
+	r, g, b := p.normalized(max)
+	kr, kb := std.lumaWeights()
+	y := kr*r + (1-kr-kb)*g + kb*b
+	return YCbCr{y, (b - y) / (2 * (1 - kb)), (r - y) / (2 * (1 - kr))}
+}
+
+// Pixel converts the color to a pixel with samples ranging up to max, using
+// the coefficients of std.
+func (c YCbCr) Pixel(max uint8, std YCbCrStandard) Pixel {
+	kr, kb := std.lumaWeights()
+	r := c.Y + 2*(1-kr)*c.Cr
+	b := c.Y + 2*(1-kb)*c.Cb
+	g := (c.Y - kr*r - kb*b) / (1 - kr - kb)
+	return pixelOf(r, g, b, max)
+}
+
+// LinearRGB decodes the sRGB transfer curve of the pixel, whose samples
+// range up to max.
+func (p Pixel) LinearRGB(max uint8) LinearRGB {
+	r, g, b := p.normalized(max)
+	return LinearRGB{srgbToLinear(r), srgbToLinear(g), srgbToLinear(b)}
+}
+
+// Pixel encodes the color with the sRGB transfer curve into a pixel with
+// samples ranging up to max.
+func (c LinearRGB) Pixel(max uint8) Pixel {
+	return Pixel{
+		encodeSample(c.R, max, true),
+		encodeSample(c.G, max, true),
+		encodeSample(c.B, max, true),
+	}
+}
+
+// XYZ converts the color from linear sRGB to CIE XYZ.
+func (c LinearRGB) XYZ() XYZ {
+	return XYZ{
+		0.4124564*c.R + 0.3575761*c.G + 0.1804375*c.B,
+		0.2126729*c.R + 0.7151522*c.G + 0.0721750*c.B,
+		0.0193339*c.R + 0.1191920*c.G + 0.9503041*c.B,
+	}
+}
+
+// LinearRGB converts the color from CIE XYZ to linear sRGB. Colors outside
+// the sRGB gamut give components outside [0, 1].
+func (c XYZ) LinearRGB() LinearRGB {
+	return LinearRGB{
+		3.2404542*c.X - 1.5371385*c.Y - 0.4985314*c.Z,
+		-0.9692660*c.X + 1.8760108*c.Y + 0.0415560*c.Z,
+		0.0556434*c.X - 0.2040259*c.Y + 1.0572252*c.Z,
+	}
+}
+
+// XYZ converts the pixel, whose samples range up to max, to CIE XYZ.
+func (p Pixel) XYZ(max uint8) XYZ {
+	return p.LinearRGB(max).XYZ()
+}
+
+// Pixel converts the color to a pixel with samples ranging up to max,
+// clipping colors outside the sRGB gamut.
+func (c XYZ) Pixel(max uint8) Pixel {
+	return c.LinearRGB().Pixel(max)
+}
+
+// Lab converts the color from CIE XYZ to CIE L*a*b*.
+func (c XYZ) Lab() Lab {
+	f := func(t float64) float64 {
+		if t > 216.0/24389 {
+			return math.Cbrt(t)
+		}
+		return (24389.0/27*t + 16) / 116
+	}
+	fx, fy, fz := f(c.X/whiteX), f(c.Y/whiteY), f(c.Z/whiteZ)
+	return Lab{116*fy - 16, 500 * (fx - fy), 200 * (fy - fz)}
+}
+
+// XYZ converts the color from CIE L*a*b* to CIE XYZ.
+func (c Lab) XYZ() XYZ {
+	finv := func(t float64) float64 {
+		if t3 := t * t * t; t3 > 216.0/24389 {
+			return t3
+		}
+		return (116*t - 16) * 27 / 24389
+	}
+	fy := (c.L + 16) / 116
+	fx := fy + c.A/500
+	fz := fy - c.B/200
+	return XYZ{whiteX * finv(fx), whiteY * finv(fy), whiteZ * finv(fz)}
+}
+
+// Lab converts the pixel, whose samples range up to max, to CIE L*a*b*.
+func (p Pixel) Lab(max uint8) Lab {
+	return p.XYZ(max).Lab()
+}
+
+// Pixel converts the color to a pixel with samples ranging up to max,
+// clipping colors outside the sRGB gamut.
+func (c Lab) Pixel(max uint8) Pixel {
+	return c.XYZ().Pixel(max)
+}
+
+// mapPixels replaces every pixel of the PPM image with f applied to it.
+func (ppm *PPM) mapPixels(f func(Pixel) Pixel) {
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			ppm.data[y][x] = f(ppm.data[y][x])
+		}
+	}
+}
+
+// RotateHue shifts the hue of every pixel of the PPM image by the given
+// number of degrees, keeping saturation and value.
+func (ppm *PPM) RotateHue(degrees float64) {
+	ppm.mapPixels(func(p Pixel) Pixel {
+		c := p.HSV(ppm.max)
+		c.H += degrees
+		return c.Pixel(ppm.max)
+	})
+}
+
+// AdjustSaturation multiplies the HSV saturation of every pixel of the PPM
+// image by factor. Zero gives a gray image and values above one make colors
+// more vivid.
+func (ppm *PPM) AdjustSaturation(factor float64) {
+	ppm.mapPixels(func(p Pixel) Pixel {
+		c := p.HSV(ppm.max)
+		c.S = math.Max(0, math.Min(c.S*factor, 1))
+		return c.Pixel(ppm.max)
+	})
+}
+
+// AdjustLightness adds delta to the CIE L* lightness of every pixel of the
+// PPM image, keeping a* and b*. Since L* is perceptually uniform, equal
+// deltas look like equal changes across the tonal range.
+func (ppm *PPM) AdjustLightness(delta float64) {
+	ppm.mapPixels(func(p Pixel) Pixel {
+		c := p.Lab(ppm.max)
+		c.L = math.Max(0, math.Min(c.L+delta, 100))
+		return c.Pixel(ppm.max)
+	})
+}
@@ -0,0 +1,173 @@
+package Netpbm
+
+import "fmt"
+
+// Component holds the statistics of a connected region of black pixels.
+// Perimeter counts the pixel edges between the region and white pixels or
+// the image border.
+type Component struct {
+	Label                int
+	Area                 int
+	Bounds               Rectangle
+	CentroidX, CentroidY float64
+	Perimeter            int
+}
+
+// find returns the root of label i, compressing the path to it.
+func find(parent []int, i int) int {
+	for parent[i] != i {
+		parent[i] = parent[parent[i]]
+		i = parent[i]
+	}
+	return i
+}
+
+// union merges the sets of labels a and b, keeping the smaller root.
+func union(parent []int, a, b int) {
+	a, b = find(parent, a), find(parent, b)
+	if a < b {
+		parent[b] = a
+	} else if b < a {
+		parent[a] = b
+	}
+}
+
+// labels returns the label map of the pixels for which in returns true,
+// with labels numbered from 1 in raster order of the first pixel of every
+// region and 0 elsewhere, along with the number of labels.
+func labels(width, height, connectivity int, in func(x, y int) bool) ([][]int, int) {
+	// Previously visited neighbors: left and above, plus the diagonals above
+	// for 8-connectivity.
+	previous := []Point{{-1, 0}, {0, -1}}
+	if connectivity == 8 {
+		previous = append(previous, Point{-1, -1}, Point{1, -1})
+	}
+	grid := newGrid[int](width, height)
+	parent := []int{0}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if !in(x, y) {
+				continue
+			}
+			label := 0
+			for _, o := range previous {
+				nx, ny := x+o.X, y+o.Y
+				if nx < 0 || ny < 0 || nx >= width || grid[ny][nx] == 0 {
+					continue
+				}
+				if label == 0 {
+					label = grid[ny][nx]
+				} else {
+					union(parent, label, grid[ny][nx])
+				}
+			}
+			if label == 0 {
+				label = len(parent)
+				parent = append(parent, label)
+			}
+			grid[y][x] = label
+		}
+	}
+
+	// Renumber the roots consecutively in raster order.
+	number := make([]int, len(parent))
+	count := 0
+	for i := 1; i < len(parent); i++ {
+		if root := find(parent, i); root == i {
+			count++
+			number[i] = count
+		}
+	}
+	for y := range grid {
+		for x, label := range grid[y] {
+			if label != 0 {
+				grid[y][x] = number[find(parent, label)]
+			}
+		}
+	}
+	return grid, count
+}
+
+// ConnectedComponents labels the connected regions of black pixels of the
+// PBM image, with 4 or 8 connectivity. It returns a label map where white
+// pixels are 0 and regions are numbered from 1 in raster order, and the
+// statistics of every region, the one labeled n at index n-1.
+func (pbm *PBM) ConnectedComponents(connectivity int) ([][]int, []Component, error) {
+	if connectivity != 4 && connectivity != 8 {
+		return nil, nil, fmt.Errorf("connectivity must be 4 or 8, not %d", connectivity)
+	}
+	grid, count := labels(pbm.width, pbm.height, connectivity, func(x, y int) bool { return pbm.data[y][x] })
+	stats := make([]Component, count)
+	sumX, sumY := make([]int, count), make([]int, count)
+	for i := range stats {
+		stats[i].Label = i + 1
+	}
+	for y := 0; y < pbm.height; y++ {
+		for x := 0; x < pbm.width; x++ {
+			label := grid[y][x]
+			if label == 0 {
+				continue
+			}
+			c := &stats[label-1]
+			pixel := Rect(x, y, x+1, y+1)
+			if c.Area == 0 {
+				c.Bounds = pixel
+			} else {
+				c.Bounds = Rectangle{
+					Point{min(c.Bounds.Min.X, x), min(c.Bounds.Min.Y, y)},
+					Point{max(c.Bounds.Max.X, x+1), max(c.Bounds.Max.Y, y+1)},
+				}
+			}
+			c.Area++
+			sumX[label-1] += x
+			sumY[label-1] += y
+			for _, o := range [4]Point{{1, 0}, {-1, 0}, {0, 1}, {0, -1}} {
+				nx, ny := x+o.X, y+o.Y
+				if nx < 0 || ny < 0 || nx >= pbm.width || ny >= pbm.height || !pbm.data[ny][nx] {
+					c.Perimeter++
+				}
+			}
+		}
+	}
+	for i := range stats {
+		stats[i].CentroidX = float64(sumX[i]) / float64(stats[i].Area)
+		stats[i].CentroidY = float64(sumY[i]) / float64(stats[i].Area)
+	}
+	return grid, stats, nil
+}
+
+// RemoveSmallObjects turns white every 8-connected region of black pixels of
+// the PBM image whose area is below minArea, which cleans speckles from
+// scanned pages.
+func (pbm *PBM) RemoveSmallObjects(minArea int) {
+	grid, stats, _ := pbm.ConnectedComponents(8)
+	for y := 0; y < pbm.height; y++ {
+		for x := 0; x < pbm.width; x++ {
+			if label := grid[y][x]; label != 0 && stats[label-1].Area < minArea {
+				pbm.data[y][x] = false
+			}
+		}
+	}
+}
+
+// FillHoles turns black every region of white pixels of the PBM image that
+// does not touch the border. White regions are 4-connected, which matches
+// black regions that are 8-connected.
+func (pbm *PBM) FillHoles() {
+	grid, count := labels(pbm.width, pbm.height, 4, func(x, y int) bool { return !pbm.data[y][x] })
+	border := make([]bool, count+1)
+	for y := 0; y < pbm.height; y++ {
+		for x := 0; x < pbm.width; x++ {
+			if x == 0 || y == 0 || x == pbm.width-1 || y == pbm.height-1 {
+				border[grid[y][x]] = true
+			}
+		}
+	}
+	for y := 0; y < pbm.height; y++ {
+		for x := 0; x < pbm.width; x++ {
+			if label := grid[y][x]; label != 0 && !border[label] {
+				pbm.data[y][x] = true
+			}
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package Netpbm
+
+import (
+	"errors"
+	"math"
+)
+
+// EdgeMode selects how filters extend an image past its borders.
+type EdgeMode int
+
+const (
+	// EdgeClamp repeats the border pixels.
+	EdgeClamp EdgeMode = iota
+	// EdgeWrap tiles the image, so pixels past the right border come from
+	// the left one.
+	EdgeWrap
+	// EdgeMirror reflects the image about its border pixels, which are not
+	// repeated.
+	EdgeMirror
+	// EdgeZero treats everything outside the image as black.
+	EdgeZero
+)
+
+// index maps the coordinate i, possibly outside [0, n), to a coordinate
+// inside it, or returns -1 for pixels that EdgeZero treats as black.
+func (mode EdgeMode) index(i, n int) int {
+	if i >= 0 && i < n {
+		return i
+	}
+	switch mode {
+	case EdgeWrap:
+		return (i%n + n) % n
+	case EdgeMirror:
+		if n == 1 {
+			return 0
+		}
+		period := 2 * (n - 1)
+		i = (i%period + period) % period
+		if i >= n {
+			i = period - i
+		}
+		return i
+	case EdgeZero:
+		return -1
+	}
+	return clamp(i, 0, n-1)
+}
+
+// Kernel is a convolution kernel. Data holds Width×Height weights row by
+// row; both sizes must be odd so the kernel has a center pixel. The weighted
+// sum is divided by Divisor, where zero means one, and Bias, a fraction of
+// the max value, is added to the result, like in pnmconvol.
+type Kernel struct {
+	Width, Height int
+	Data          []float64
+	Divisor       float64
+	Bias          float64
+}
+
+// validate checks the size of the kernel against its data.
+func (k *Kernel) validate() error {
+	if k.Width <= 0 || k.Height <= 0 || k.Width%2 == 0 || k.Height%2 == 0 {
+		return errors.New("kernel sizes must be odd and positive")
+	}
+	if len(k.Data) != k.Width*k.Height {
+		return errors.New("kernel data does not match its size")
+	}
+	return nil
+}
+
+// divisor returns the divisor of the kernel, treating zero as one.
+func (k *Kernel) divisor() float64 {
+	if k.Divisor == 0 {
+		return 1
+	}
+	return k.Divisor
+}
+
+// Normalize sets the divisor of the kernel to the sum of its weights so that
+// it keeps the overall brightness of the image. Kernels whose weights sum to
+// zero, such as edge detectors, are left unchanged.
+func (k *Kernel) Normalize() {
+	sum := 0.0
+	for _, w := range k.Data {
+		sum += w
+	}
+	if sum != 0 {
+		k.Divisor = sum
+	}
+}
+
+// Separable reports whether the kernel is the outer product of a column and
+// a row vector and returns them, with the divisor folded into the row.
+// Convolving with the two vectors in turn is much faster for large kernels.
+func (k *Kernel) Separable() (row, col []float64, ok bool) {
+	if k.validate() != nil {
+		return nil, nil, false
+	}
+	// Take the row and column of the largest weight as the factors.
+	pivot, largest := 0, 0.0
+	for i, w := range k.Data {
+		if math.Abs(w) > largest {
+			pivot, largest = i, math.Abs(w)
+		}
+	}
+	if largest == 0 {
+		return nil, nil, false
+	}
+	px, py := pivot%k.Width, pivot/k.Width
+	p := k.Data[pivot]
+	row = make([]float64, k.Width)
+	col = make([]float64, k.Height)
+	for x := range row {
+		row[x] = k.Data[py*k.Width+x] / k.divisor()
+	}
+	for y := range col {
+		col[y] = k.Data[y*k.Width+px] / p
+	}
+	for y := 0; y < k.Height; y++ {
+		for x := 0; x < k.Width; x++ {
+			want := k.Data[y*k.Width+x] / k.divisor()
+			if math.Abs(col[y]*row[x]-want) > 1e-9*math.Max(1, math.Abs(want)) {
+				return nil, nil, false
+			}
+		}
+	}
+	return row, col, true
+}
+
+// integral reports whether every weight of the kernel is a whole number,
+// which allows summing samples with integer arithmetic.
+func (k *Kernel) integral() bool {
+	for _, w := range k.Data {
+		if w != math.Trunc(w) || math.Abs(w) > 1<<20 {
+			return false
+		}
+	}
+	return true
+}
+
+// BoxKernel returns a normalized square kernel of the given radius whose
+// weights are all equal, which averages every pixel with its neighbors.
+func BoxKernel(radius int) *Kernel {
+	size := 2*max(radius, 0) + 1
+	data := make([]float64, size*size)
+	for i := range data {
+		data[i] = 1
+	}
+	return &Kernel{size, size, data, float64(len(data)), 0}
+}
+
+// GaussianKernel returns a normalized Gaussian blur kernel of standard
+// deviation sigma, truncated at three sigmas.
+func GaussianKernel(sigma float64) *Kernel {
+	if sigma <= 0 {
+		return &Kernel{1, 1, []float64{1}, 0, 0}
+	}
+	weights := gaussianWeights(sigma)
+	size := len(weights)
+	data := make([]float64, size*size)
+	for y, wy := range weights {
+		for x, wx := range weights {
+			data[y*size+x] = wy * wx
+		}
+	}
+	return &Kernel{size, size, data, 0, 0}
+}
+
+// SharpenKernel returns a 3×3 kernel that sharpens the image by subtracting
+// the four neighbors of every pixel from a boosted center.
+func SharpenKernel() *Kernel {
+	return &Kernel{3, 3, []float64{
+		0, -1, 0,
+		-1, 5, -1,
+		0, -1, 0,
+	}, 0, 0}
+}
+
+// EmbossKernel returns a 3×3 kernel that renders edges as a relief lit from
+// the top left, on a mid-gray background.
+func EmbossKernel() *Kernel {
+	return &Kernel{3, 3, []float64{
+		-1, -1, 0,
+		-1, 0, 1,
+		0, 1, 1,
+	}, 0, 0.5}
+}
+
+// LaplacianKernel returns the 3×3 Laplacian, which responds to edges in any
+// direction. Negative responses clip to black; set Bias to 0.5 to see both
+// sides of an edge.
+func LaplacianKernel() *Kernel {
+	return &Kernel{3, 3, []float64{
+		0, 1, 0,
+		1, -4, 1,
+		0, 1, 0,
+	}, 0, 0}
+}
+
+// convolvePlane returns p convolved with k, extending the borders according
+// to mode.
+func convolvePlane(p *plane, k *Kernel, mode EdgeMode) *plane {
+	var out *plane
+	if row, col, ok := k.Separable(); ok && k.Width > 1 && k.Height > 1 {
+		out = p.separable(row, col, mode)
+	} else {
+		out = newPlane(p.width, p.height)
+		rx, ry := k.Width/2, k.Height/2
+		d := k.divisor()
+		for y := 0; y < p.height; y++ {
+			for x := 0; x < p.width; x++ {
+				sum := 0.0
+				for ky := 0; ky < k.Height; ky++ {
+					sy := mode.index(y+ky-ry, p.height)
+					if sy < 0 {
+						continue
+					}
+					for kx := 0; kx < k.Width; kx++ {
+						if sx := mode.index(x+kx-rx, p.width); sx >= 0 {
+							sum += k.Data[ky*k.Width+kx] * p.at(sx, sy)
+						}
+					}
+				}
+				out.set(x, y, sum/d)
+			}
+		}
+	}
+	if k.Bias != 0 {
+		for i := range out.pix {
+			out.pix[i] += k.Bias
+		}
+	}
+	return out
+}
+
+// convolveIntegral convolves the PGM image with a kernel of whole weights
+// using integer sums, which is exact and avoids converting to floats.
+func (pgm *PGM) convolveIntegral(k *Kernel, mode EdgeMode) {
+	weights := make([]int, len(k.Data))
+	for i, w := range k.Data {
+		weights[i] = int(w)
+	}
+	rx, ry := k.Width/2, k.Height/2
+	d := k.divisor()
+	bias := k.Bias * float64(pgm.max)
+	out := newGrid[uint8](pgm.width, pgm.height)
+	for y := 0; y < pgm.height; y++ {
+		for x := 0; x < pgm.width; x++ {
+			sum := 0
+			for ky := 0; ky < k.Height; ky++ {
+				sy := mode.index(y+ky-ry, pgm.height)
+				if sy < 0 {
+					continue
+				}
+				for kx := 0; kx < k.Width; kx++ {
+					if sx := mode.index(x+kx-rx, pgm.width); sx >= 0 {
+						sum += weights[ky*k.Width+kx] * int(pgm.data[sy][sx])
+					}
+				}
+			}
+			v := math.Round(float64(sum)/d + bias)
+			out[y][x] = uint8(math.Max(0, math.Min(v, float64(pgm.max))))
+		}
+	}
+	for y := range out {
+		copy(pgm.data[y], out[y])
+	}
+}
+
+// Convolve applies the kernel to the PGM image, extending the borders
+// according to mode, like pnmconvol. Samples are filtered as stored, without
+// decoding to linear light. Separable kernels are applied as a row and a
+// column pass, and other kernels of whole weights use integer arithmetic.
+func (pgm *PGM) Convolve(k *Kernel, mode EdgeMode) error {
+	if err := k.validate(); err != nil {
+		return err
+	}
+	pgm.convolve(k, mode)
+	return nil
+}
+
+// convolve applies a validated kernel to the PGM image.
+func (pgm *PGM) convolve(k *Kernel, mode EdgeMode) {
+	if _, _, ok := k.Separable(); (!ok || k.Width == 1 || k.Height == 1) && k.integral() {
+		pgm.convolveIntegral(k, mode)
+		return
+	}
+	pgm.setPlane(convolvePlane(pgm.plane(false), k, mode), false)
+}
+
+// Convolve applies the kernel to every channel of the PPM image, extending
+// the borders according to mode.
+func (ppm *PPM) Convolve(k *Kernel, mode EdgeMode) error {
+	if err := k.validate(); err != nil {
+		return err
+	}
+	for _, c := range []Channel{Red, Green, Blue} {
+		channel := ppm.ExtractChannel(c)
+		channel.convolve(k, mode)
+		for y := 0; y < ppm.height; y++ {
+			for x := 0; x < ppm.width; x++ {
+				c.set(&ppm.data[y][x], channel.data[y][x])
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package Netpbm
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestPPMConvolveMatchesChannels(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	kernels := []*Kernel{BoxKernel(1), GaussianKernel(1.2), SharpenKernel(), EmbossKernel(), LaplacianKernel()}
+	for _, mode := range []EdgeMode{EdgeClamp, EdgeWrap, EdgeMirror, EdgeZero} {
+		for i, k := range kernels {
+			ppm := randomPPM(r, 9, 7, 200)
+			want := [3]*PGM{}
+			for c, channel := range []Channel{Red, Green, Blue} {
+				want[c] = ppm.ExtractChannel(channel)
+				if err := want[c].Convolve(k, mode); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := ppm.Convolve(k, mode); err != nil {
+				t.Fatal(err)
+			}
+			for c, channel := range []Channel{Red, Green, Blue} {
+				if got := ppm.ExtractChannel(channel); !got.Equal(want[c]) {
+					t.Errorf("mode %d kernel %d: channel %d differs from the PGM convolution", mode, i, c)
+				}
+			}
+		}
+	}
+}
+
+func TestConvolveInvalidKernel(t *testing.T) {
+	kernels := []*Kernel{
+		{Width: 2, Height: 3, Data: make([]float64, 6)},
+		{Width: 3, Height: 3, Data: make([]float64, 8)},
+	}
+	for i, k := range kernels {
+		pgm := flatPGM(4, 4, 10, 255)
+		if err := pgm.Convolve(k, EdgeClamp); err == nil {
+			t.Errorf("PGM.Convolve accepted kernel %d", i)
+		}
+		ppm := randomPPM(rand.New(rand.NewSource(2)), 4, 4, 255)
+		want := ppm.Clone()
+		if err := ppm.Convolve(k, EdgeClamp); err == nil {
+			t.Errorf("PPM.Convolve accepted kernel %d", i)
+		}
+		if !ppm.Equal(want) {
+			t.Errorf("PPM.Convolve changed the image with kernel %d", i)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package Netpbm
+
+import "math"
+
+// defaultMaxSkew is the search range, in degrees, used by EstimateSkew and
+// Deskew when no positive range is given.
+const defaultMaxSkew = 15
+
+// EstimateSkew returns the angle, in degrees counter-clockwise, by which the
+// text lines of the PBM image are tilted, searching within ±maxAngle.
+// It uses the projection-profile method: black pixels are projected onto
+// rows at candidate angles, and the angle giving the sharpest profile wins.
+// A coarse search in half-degree steps is refined to a twentieth of a degree.
+func (pbm *PBM) EstimateSkew(maxAngle float64) float64 {
+	if maxAngle <= 0 {
+		maxAngle = defaultMaxSkew
+	}
+
+	// Collect black pixels relative to the image center.
+	var xs, ys []float64
+	cx, cy := float64(pbm.width-1)/2, float64(pbm.height-1)/2
+	for y := 0; y < pbm.height; y++ {
+		for x := 0; x < pbm.width; x++ {
+			if pbm.data[y][x] {
+				xs = append(xs, float64(x)-cx)
+				ys = append(ys, float64(y)-cy)
+			}
+		}
+	}
+	if len(xs) == 0 {
+		return 0
+	}
+
+	radius := math.Hypot(float64(pbm.width), float64(pbm.height))/2 + 1
+	bins := make([]float64, 2*int(radius)+1)
+
+	// score measures how sharp the row profile is once the pixels are
+	// rotated counter-clockwise by angle degrees.
+	score := func(angle float64) float64 {
+		sin, cos := math.Sincos(angle * math.Pi / 180)
+		for i := range bins {
+			bins[i] = 0
+		}
+		for i := range xs {
+			bins[int(-sin*xs[i]+cos*ys[i]+radius)]++
+		}
+		sum := 0.0
+		for _, n := range bins {
+			sum += n * n
+		}
+		return sum
+	}
+
+	// search returns the best angle in [center-span, center+span] sampled
+	// every step degrees. Profiles often stay equally sharp over a small
+	// range of angles, in which case the middle of that range is returned.
+	search := func(center, span, step float64) float64 {
+		n := int(math.Round(span / step))
+		first, last, bestScore := -n, -n, -1.0
+		for i := -n; i <= n; i++ {
+			s := score(center + float64(i)*step)
+			if s > bestScore {
+				first, last, bestScore = i, i, s
+			} else if s == bestScore && last == i-1 {
+				last = i
+			}
+		}
+		return center + float64(first+last)/2*step
+	}
+
+	correction := search(0, maxAngle, 0.5)
+	correction = search(correction, 0.5, 0.05)
+	skew := math.Round(-correction*100) / 100
+	if skew == 0 {
+		return 0 // avoid reporting a negative zero
+	}
+	return skew
+}
+
+// Deskew rotates the PBM image to straighten its text lines and returns the
+// skew angle found by EstimateSkew. The canvas size is kept and uncovered
+// areas are left white.
+func (pbm *PBM) Deskew(maxAngle float64) float64 {
+	skew := pbm.EstimateSkew(maxAngle)
+	if skew != 0 {
+		pbm.Rotate(-skew, NearestNeighbor, false, RotateKeepSize)
+	}
+	return skew
+}
+
+// EstimateSkew returns the angle, in degrees counter-clockwise, by which the
+// text lines of the PGM image are tilted, searching within ±maxAngle.
+// The image is binarized with Otsu's method to find the dark text first.
+func (pgm *PGM) EstimateSkew(maxAngle float64) float64 {
+	return pgm.Binarize(BinarizeOptions{Method: Otsu}).EstimateSkew(maxAngle)
+}
+
+// Deskew rotates the PGM image to straighten its text lines and returns the
+// skew angle found by EstimateSkew. The canvas size is kept and uncovered
+// areas are filled with the max value (white).
+func (pgm *PGM) Deskew(maxAngle float64) float64 {
+	skew := pgm.EstimateSkew(maxAngle)
+	if skew != 0 {
+		pgm.Rotate(-skew, Bilinear, pgm.max, RotateKeepSize)
+	}
+	return skew
+}
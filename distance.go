@@ -0,0 +1,217 @@
+package Netpbm
+
+import "math"
+
+// distance1D computes in d the squared distance transform of the sampled
+// function f along one dimension with the lower envelope of parabolas of
+// Felzenszwalb and Huttenlocher. v and z are scratch buffers of len(f) and
+// len(f)+1 entries.
+func distance1D(f, d []float64, v []int, z []float64) {
+	n := len(f)
+	k := -1
+	for q := 0; q < n; q++ {
+		if math.IsInf(f[q], 1) {
+			continue
+		}
+		for k >= 0 {
+			s := ((f[q] + float64(q*q)) - (f[v[k]] + float64(v[k]*v[k]))) / float64(2*(q-v[k]))
+			if s > z[k] {
+				break
+			}
+			k--
+		}
+		k++
+		v[k] = q
+		if k == 0 {
+			z[k] = math.Inf(-1)
+		} else {
+			z[k] = ((f[q] + float64(q*q)) - (f[v[k-1]] + float64(v[k-1]*v[k-1]))) / float64(2*(q-v[k-1]))
+		}
+		z[k+1] = math.Inf(1)
+	}
+	if k < 0 {
+		for q := range d {
+			d[q] = math.Inf(1)
+		}
+		return
+	}
+	j := 0
+	for q := 0; q < n; q++ {
+		for z[j+1] < float64(q) {
+			j++
+		}
+		dq := float64(q - v[j])
+		d[q] = dq*dq + f[v[j]]
+	}
+}
+
+// squaredDistances returns the squared Euclidean distance from every pixel
+// to the nearest pixel for which in returns false, in linear time. Those
+// pixels are at distance zero, and the others are at an infinite distance
+// when there is none.
+func squaredDistances(width, height int, in func(x, y int) bool) [][]float64 {
+	dist := newGrid[float64](width, height)
+	n := max(width, height)
+	f, d := make([]float64, n), make([]float64, n)
+	v, z := make([]int, n), make([]float64, n+1)
+
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			if in(x, y) {
+				f[y] = math.Inf(1)
+			} else {
+				f[y] = 0
+			}
+		}
+		distance1D(f[:height], d[:height], v, z)
+		for y := 0; y < height; y++ {
+			dist[y][x] = d[y]
+		}
+	}
+	for y := 0; y < height; y++ {
+		copy(f, dist[y])
+		distance1D(f[:width], dist[y], v, z)
+	}
+	return dist
+}
+
+// squaredEDT returns the squared Euclidean distance from every pixel of the
+// PBM image to the nearest white pixel.
+func (pbm *PBM) squaredEDT() [][]float64 {
+	return squaredDistances(pbm.width, pbm.height, func(x, y int) bool { return pbm.data[y][x] })
+}
+
+// DistanceMetric selects how DistanceTransform measures distances.
+type DistanceMetric int
+
+const (
+	// Euclidean gives exact straight-line distances, computed in linear time
+	// with the algorithm of Felzenszwalb and Huttenlocher.
+	Euclidean DistanceMetric = iota
+	// Chamfer approximates Euclidean distances with steps of 3 to edge
+	// neighbors and 4 to diagonal ones, divided by 3.
+	Chamfer
+	// Manhattan counts steps between edge neighbors.
+	Manhattan
+	// Chessboard counts steps between edge or diagonal neighbors.
+	Chessboard
+)
+
+// chamfer returns the distances computed with a forward and a backward
+// raster scan, where edge steps cost a and diagonal steps cost b.
+func (pbm *PBM) chamfer(a, b float64) [][]float64 {
+	w, h := pbm.width, pbm.height
+	dist := newGrid[float64](w, h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if pbm.data[y][x] {
+				dist[y][x] = math.Inf(1)
+			}
+		}
+	}
+	forward := [4]Point{{-1, 0}, {-1, -1}, {0, -1}, {1, -1}}
+	relax := func(x, y int, steps [4]Point) {
+		for _, o := range steps {
+			nx, ny := x+o.X, y+o.Y
+			if nx < 0 || ny < 0 || nx >= w || ny >= h {
+				continue
+			}
+			cost := a
+			if o.X != 0 && o.Y != 0 {
+				cost = b
+			}
+			dist[y][x] = math.Min(dist[y][x], dist[ny][nx]+cost)
+		}
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			relax(x, y, forward)
+		}
+	}
+	backward := [4]Point{{1, 0}, {1, 1}, {0, 1}, {-1, 1}}
+	for y := h - 1; y >= 0; y-- {
+		for x := w - 1; x >= 0; x-- {
+			relax(x, y, backward)
+		}
+	}
+	return dist
+}
+
+// DistanceTransform returns the distance from every pixel of the PBM image
+// to the nearest white pixel with the given metric. White pixels are at
+// distance zero, and black pixels are at an infinite distance when the
+// image has no white pixel. Pixels outside the image are not considered.
+func (pbm *PBM) DistanceTransform(metric DistanceMetric) [][]float64 {
+	switch metric {
+	case Chamfer:
+		dist := pbm.chamfer(3, 4)
+		for y := range dist {
+			for x := range dist[y] {
+				dist[y][x] /= 3
+			}
+		}
+		return dist
+	case Manhattan:
+		return pbm.chamfer(1, 2)
+	case Chessboard:
+		return pbm.chamfer(1, 1)
+	}
+	dist := pbm.squaredEDT()
+	for y := range dist {
+		for x := range dist[y] {
+			dist[y][x] = math.Sqrt(dist[y][x])
+		}
+	}
+	return dist
+}
+
+// DistanceTransformPGM returns the distance transform of the PBM image as a
+// PGM image with a max value of 255, where every distance is rounded to the
+// nearest level and clamped to 255.
+func (pbm *PBM) DistanceTransformPGM(metric DistanceMetric) *PGM {
+	dist := pbm.DistanceTransform(metric)
+	pgm := &PGM{newGrid[uint8](pbm.width, pbm.height), pbm.width, pbm.height, "P2", 255}
+	for y := range dist {
+		for x, d := range dist[y] {
+			pgm.data[y][x] = uint8(math.Min(math.Round(d), 255))
+		}
+	}
+	return pgm
+}
+
+// ErodeDisk erodes the PBM image by a disk of the given radius, giving the
+// same result as Erode with DiskElement(radius) but in time independent of
+// the radius. Pixels outside the image count as white.
+func (pbm *PBM) ErodeDisk(radius int) {
+	if radius <= 0 {
+		return
+	}
+	dist := pbm.squaredEDT()
+	r2 := float64(radius * radius)
+	for y := 0; y < pbm.height; y++ {
+		for x := 0; x < pbm.width; x++ {
+			border := min(x+1, y+1, pbm.width-x, pbm.height-y)
+			if float64(border*border) <= r2 || dist[y][x] <= r2 {
+				pbm.data[y][x] = false
+			}
+		}
+	}
+}
+
+// DilateDisk dilates the PBM image by a disk of the given radius, giving the
+// same result as Dilate with DiskElement(radius) but in time independent of
+// the radius.
+func (pbm *PBM) DilateDisk(radius int) {
+	if radius <= 0 {
+		return
+	}
+	dist := squaredDistances(pbm.width, pbm.height, func(x, y int) bool { return !pbm.data[y][x] })
+	r2 := float64(radius * radius)
+	for y := 0; y < pbm.height; y++ {
+		for x := 0; x < pbm.width; x++ {
+			if dist[y][x] <= r2 {
+				pbm.data[y][x] = true
+			}
+		}
+	}
+}
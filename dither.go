@@ -0,0 +1,312 @@
+package Netpbm
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+)
+
+// DitherMethod selects how ToPBMWith turns gray levels into black and white.
+type DitherMethod int
+
+const (
+	// DitherThreshold compares every pixel against half the max value,
+	// like ToPBM.
+	DitherThreshold DitherMethod = iota
+	FloydSteinberg
+	Atkinson
+	JarvisJudiceNinke
+	Stucki
+	Sierra
+	// Bayer uses an ordered dither matrix of DitherOptions.BayerSize.
+	Bayer
+	// BlueNoise thresholds against a tiled blue-noise texture, which avoids
+	// the cross-hatch pattern of Bayer matrices.
+	BlueNoise
+)
+
+// DitherOptions configures ToPBMWith, like the options of pamditherbw.
+type DitherOptions struct {
+	Method DitherMethod
+	// BayerSize is the side of the Bayer matrix: 2, 4, 8 or 16.
+	// Zero selects 8.
+	BayerSize int
+	// Serpentine alternates the scan direction on every row for the error
+	// diffusion methods, which reduces directional artifacts.
+	Serpentine bool
+}
+
+// diffusionTap is one neighbor receiving a share of the quantization error.
+type diffusionTap struct {
+	dx, dy int
+	weight float64
+}
+
+// diffusionKernel describes how the quantization error of a pixel is spread
+// over the pixels that have not been processed yet.
+type diffusionKernel struct {
+	taps    []diffusionTap
+	divisor float64
+}
+
+var diffusionKernels = map[DitherMethod]diffusionKernel{
+	FloydSteinberg: {[]diffusionTap{
+		{1, 0, 7},
+		{-1, 1, 3}, {0, 1, 5}, {1, 1, 1},
+	}, 16},
+	// Atkinson only spreads six eighths of the error, trading some tonal
+	// accuracy for crisper highlights and shadows.
+	Atkinson: {[]diffusionTap{
+		{1, 0, 1}, {2, 0, 1},
+		{-1, 1, 1}, {0, 1, 1}, {1, 1, 1},
+		{0, 2, 1},
+	}, 8},
+	JarvisJudiceNinke: {[]diffusionTap{
+		{1, 0, 7}, {2, 0, 5},
+		{-2, 1, 3}, {-1, 1, 5}, {0, 1, 7}, {1, 1, 5}, {2, 1, 3},
+		{-2, 2, 1}, {-1, 2, 3}, {0, 2, 5}, {1, 2, 3}, {2, 2, 1},
+	}, 48},
+	Stucki: {[]diffusionTap{
+		{1, 0, 8}, {2, 0, 4},
+		{-2, 1, 2}, {-1, 1, 4}, {0, 1, 8}, {1, 1, 4}, {2, 1, 2},
+		{-2, 2, 1}, {-1, 2, 2}, {0, 2, 4}, {1, 2, 2}, {2, 2, 1},
+	}, 42},
+	Sierra: {[]diffusionTap{
+		{1, 0, 5}, {2, 0, 3},
+		{-2, 1, 2}, {-1, 1, 4}, {0, 1, 5}, {1, 1, 4}, {2, 1, 2},
+		{-1, 2, 2}, {0, 2, 3}, {1, 2, 2},
+	}, 32},
+}
+
+// diffuse runs error diffusion over planes of equal size. For every pixel,
+// quantize receives the values corrected by the errors diffused so far and
+// returns the values actually output; the differences are then spread to
+// the neighbors. On serpentine scans, odd rows run right to left with the
+// kernel mirrored.
+func (k diffusionKernel) diffuse(ps []*plane, serpentine bool, quantize func(x, y int, v []float64) []float64) {
+	width, height := ps[0].width, ps[0].height
+	values := make([]float64, len(ps))
+	for y := 0; y < height; y++ {
+		dir, x := 1, 0
+		if serpentine && y%2 == 1 {
+			dir, x = -1, width-1
+		}
+		for ; x >= 0 && x < width; x += dir {
+			for c, p := range ps {
+				values[c] = p.at(x, y)
+			}
+			out := quantize(x, y, values)
+			for c, p := range ps {
+				e := (values[c] - out[c]) / k.divisor
+				for _, tap := range k.taps {
+					nx, ny := x+tap.dx*dir, y+tap.dy
+					if nx >= 0 && nx < width && ny < height {
+						p.set(nx, ny, p.at(nx, ny)+e*tap.weight)
+					}
+				}
+			}
+		}
+	}
+}
+
+// bayerMatrix returns the size×size Bayer index matrix, built recursively
+// from the 2×2 one. size must be a power of two.
+func bayerMatrix(size int) [][]int {
+	m := [][]int{{0}}
+	for n := 1; n < size; n *= 2 {
+		next := newGrid[int](2*n, 2*n)
+		for y := 0; y < n; y++ {
+			for x := 0; x < n; x++ {
+				v := 4 * m[y][x]
+				next[y][x] = v
+				next[y][x+n] = v + 2
+				next[y+n][x] = v + 3
+				next[y+n][x+n] = v + 1
+			}
+		}
+		m = next
+	}
+	return m
+}
+
+// bayerThresholds returns the Bayer matrix of the given size as thresholds
+// in (0, 1).
+func bayerThresholds(size int) [][]float64 {
+	switch size {
+	case 2, 4, 8, 16:
+	default:
+		size = 8
+	}
+	m := bayerMatrix(size)
+	t := newGrid[float64](size, size)
+	for y := range m {
+		for x := range m[y] {
+			t[y][x] = (float64(m[y][x]) + 0.5) / float64(size*size)
+		}
+	}
+	return t
+}
+
+const blueNoiseSize = 64
+
+var (
+	blueNoiseOnce  sync.Once
+	blueNoiseTable [][]float64
+)
+
+// blueNoiseThresholds returns a tileable blue-noise threshold texture,
+// generated once with Ulichney's void-and-cluster method.
+func blueNoiseThresholds() [][]float64 {
+	blueNoiseOnce.Do(func() {
+		blueNoiseTable = voidAndCluster(blueNoiseSize, 1.5)
+	})
+	return blueNoiseTable
+}
+
+// voidAndCluster generates a size×size blue-noise threshold texture. The
+// energy of every cell is the sum of a toroidal Gaussian centered on each
+// set cell; clusters are the set cells of highest energy and voids the
+// empty cells of lowest energy.
+func voidAndCluster(size int, sigma float64) [][]float64 {
+	n := size * size
+	gauss := make([]float64, n)
+	for dy := 0; dy < size; dy++ {
+		for dx := 0; dx < size; dx++ {
+			wx, wy := float64(min(dx, size-dx)), float64(min(dy, size-dy))
+			gauss[dy*size+dx] = math.Exp(-(wx*wx + wy*wy) / (2 * sigma * sigma))
+		}
+	}
+
+	// update adds sign times the Gaussian centered on cell i to energy.
+	update := func(energy []float64, i int, sign float64) {
+		ix, iy := i%size, i/size
+		for y := 0; y < size; y++ {
+			dy := (y - iy + size) % size
+			for x := 0; x < size; x++ {
+				energy[y*size+x] += sign * gauss[dy*size+(x-ix+size)%size]
+			}
+		}
+	}
+	// extreme returns the set (or empty) cell of highest (or lowest) energy.
+	extreme := func(pattern []bool, energy []float64, set bool) int {
+		best := -1
+		for i := range pattern {
+			if pattern[i] != set {
+				continue
+			}
+			if best < 0 || (set && energy[i] > energy[best]) || (!set && energy[i] < energy[best]) {
+				best = i
+			}
+		}
+		return best
+	}
+
+	// Start from a random pattern with a tenth of the cells set and move
+	// points from the tightest cluster to the largest void until stable.
+	rng := rand.New(rand.NewSource(1))
+	prototype := make([]bool, n)
+	energy := make([]float64, n)
+	for ones := 0; ones < n/10; {
+		i := rng.Intn(n)
+		if !prototype[i] {
+			prototype[i] = true
+			update(energy, i, 1)
+			ones++
+		}
+	}
+	for i := 0; i < n; i++ {
+		cluster := extreme(prototype, energy, true)
+		prototype[cluster] = false
+		update(energy, cluster, -1)
+		void := extreme(prototype, energy, false)
+		prototype[void] = true
+		update(energy, void, 1)
+		if void == cluster {
+			break
+		}
+	}
+	ones := n / 10
+
+	rank := make([]int, n)
+
+	// Rank the prototype's points by removing the tightest cluster first.
+	pattern := append([]bool(nil), prototype...)
+	e := append([]float64(nil), energy...)
+	for r := ones - 1; r >= 0; r-- {
+		cluster := extreme(pattern, e, true)
+		pattern[cluster] = false
+		update(e, cluster, -1)
+		rank[cluster] = r
+	}
+
+	// Rank the remaining cells by filling the largest void first.
+	copy(pattern, prototype)
+	for r := ones; r < n; r++ {
+		void := extreme(pattern, energy, false)
+		pattern[void] = true
+		update(energy, void, 1)
+		rank[void] = r
+	}
+
+	t := newGrid[float64](size, size)
+	for i, r := range rank {
+		t[i/size][i%size] = (float64(r) + 0.5) / float64(n)
+	}
+	return t
+}
+
+// dither converts a plane of normalized gray levels to a PBM image.
+func dither(p *plane, opts DitherOptions) *PBM {
+	pbm := &PBM{newGrid[bool](p.width, p.height), p.width, p.height, "P1"}
+
+	var thresholds [][]float64
+	switch opts.Method {
+	case Bayer:
+		thresholds = bayerThresholds(opts.BayerSize)
+	case BlueNoise:
+		thresholds = blueNoiseThresholds()
+	}
+	if thresholds != nil {
+		size := len(thresholds)
+		for y := 0; y < p.height; y++ {
+			for x := 0; x < p.width; x++ {
+				pbm.data[y][x] = p.at(x, y) < thresholds[y%size][x%size]
+			}
+		}
+		return pbm
+	}
+
+	kernel, ok := diffusionKernels[opts.Method]
+	if !ok {
+		for y := 0; y < p.height; y++ {
+			for x := 0; x < p.width; x++ {
+				pbm.data[y][x] = p.at(x, y) < 0.5
+			}
+		}
+		return pbm
+	}
+	black, white := []float64{0}, []float64{1}
+	kernel.diffuse([]*plane{p}, opts.Serpentine, func(x, y int, v []float64) []float64 {
+		if v[0] < 0.5 {
+			pbm.data[y][x] = true
+			return black
+		}
+		return white
+	})
+	return pbm
+}
+
+// ToPBMWith converts the PGM image to a PBM image using the dithering
+// method selected in opts, like pamditherbw.
+func (pgm *PGM) ToPBMWith(opts DitherOptions) *PBM {
+	if opts.Method == DitherThreshold {
+		return pgm.ToPBM()
+	}
+	return dither(pgm.plane(false), opts)
+}
+
+// ToPBMWith converts the PPM image to a PBM image using the dithering
+// method selected in opts, applied to the luma of each pixel.
+func (ppm *PPM) ToPBMWith(opts DitherOptions) *PBM {
+	return ppm.luma().ToPBMWith(opts)
+}
@@ -0,0 +1,174 @@
+package Netpbm
+
+import "math"
+
+// GradientOperator selects the kernels used to estimate image gradients.
+type GradientOperator int
+
+const (
+	// Sobel smooths across the derivative with weights 1, 2, 1.
+	Sobel GradientOperator = iota
+	// Prewitt smooths across the derivative with equal weights.
+	Prewitt
+	// Scharr smooths with weights 3, 10, 3, which gives more accurate
+	// gradient directions.
+	Scharr
+)
+
+// smoothing returns the normalized weights the operator applies across the
+// derivative.
+func (op GradientOperator) smoothing() []float64 {
+	switch op {
+	case Prewitt:
+		return []float64{1.0 / 3, 1.0 / 3, 1.0 / 3}
+	case Scharr:
+		return []float64{3.0 / 16, 10.0 / 16, 3.0 / 16}
+	}
+	return []float64{0.25, 0.5, 0.25}
+}
+
+// gradient returns the horizontal and vertical derivatives of p, scaled so
+// that a sharp step from 0 to 1 gives 1. y grows downward.
+func (p *plane) gradient(op GradientOperator) (gx, gy *plane) {
+	derivative := []float64{-1, 0, 1}
+	s := op.smoothing()
+	return p.separable(derivative, s, EdgeClamp), p.separable(s, derivative, EdgeClamp)
+}
+
+// gradientImages turns the derivatives into magnitude and direction images
+// with the given max value. Directions are measured counterclockwise from
+// the x axis, pointing toward brighter levels, with [0, 360) degrees mapped
+// to [0, max].
+func gradientImages(gx, gy *plane, magicNumber string, maxValue uint8) (magnitude, direction *PGM) {
+	w, h := gx.width, gx.height
+	magnitude = &PGM{newGrid[uint8](w, h), w, h, magicNumber, maxValue}
+	direction = &PGM{newGrid[uint8](w, h), w, h, magicNumber, maxValue}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			dx, dy := gx.at(x, y), -gy.at(x, y)
+			magnitude.data[y][x] = toSample(math.Hypot(dx, dy), maxValue)
+			angle := math.Atan2(dy, dx)
+			if angle < 0 {
+				angle += 2 * math.Pi
+			}
+			levels := int(maxValue) + 1
+			direction.data[y][x] = uint8(int(angle/(2*math.Pi)*float64(levels)) % levels)
+		}
+	}
+	return magnitude, direction
+}
+
+// Gradient estimates the gradient of the PGM image with the given operator
+// and returns its magnitude and direction as PGM images with the max value
+// of the image. A sharp step from black to white has the max magnitude and
+// steeper gradients are clipped. Directions are measured counterclockwise
+// from the x axis, pointing toward brighter levels, with [0, 360) degrees
+// mapped to [0, max].
+func (pgm *PGM) Gradient(op GradientOperator) (magnitude, direction *PGM) {
+	gx, gy := pgm.plane(false).gradient(op)
+	return gradientImages(gx, gy, pgm.magicNumber, pgm.max)
+}
+
+// Gradient estimates the gradient of the luma of the PPM image, like
+// PGM.Gradient.
+func (ppm *PPM) Gradient(op GradientOperator) (magnitude, direction *PGM) {
+	return ppm.luma().Gradient(op)
+}
+
+// canny returns the edges of p found with the Canny detector. low and high
+// are the hysteresis thresholds on the gradient magnitude.
+func canny(p *plane, sigma, low, high float64) *PBM {
+	if low > high {
+		low, high = high, low
+	}
+	gx, gy := p.gaussianBlur(sigma).gradient(Sobel)
+	w, h := p.width, p.height
+	magnitude := newPlane(w, h)
+	for i := range magnitude.pix {
+		magnitude.pix[i] = math.Hypot(gx.pix[i], gy.pix[i])
+	}
+
+	// Keep only the pixels whose magnitude is a maximum across the edge,
+	// comparing with the two neighbors along the gradient direction rounded
+	// to a multiple of 45 degrees.
+	at := func(x, y int) float64 {
+		if x < 0 || y < 0 || x >= w || y >= h {
+			return 0
+		}
+		return magnitude.at(x, y)
+	}
+	const (
+		none = iota
+		weak
+		strong
+	)
+	class := newGrid[uint8](w, h)
+	var stack []Point
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			m := magnitude.at(x, y)
+			if m < low || m == 0 {
+				continue
+			}
+			angle := math.Mod(math.Atan2(gy.at(x, y), gx.at(x, y))+math.Pi, math.Pi)
+			var dx, dy int
+			switch sector := int(math.Round(angle/(math.Pi/4))) % 4; sector {
+			case 0:
+				dx, dy = 1, 0
+			case 1:
+				dx, dy = 1, 1
+			case 2:
+				dx, dy = 0, 1
+			default:
+				dx, dy = -1, 1
+			}
+			if m < at(x+dx, y+dy) || m < at(x-dx, y-dy) {
+				continue
+			}
+			if m >= high {
+				class[y][x] = strong
+				stack = append(stack, Point{x, y})
+			} else {
+				class[y][x] = weak
+			}
+		}
+	}
+
+	// Hysteresis: weak pixels are kept when they connect to a strong one.
+	for len(stack) > 0 {
+		q := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				x, y := q.X+dx, q.Y+dy
+				if x >= 0 && y >= 0 && x < w && y < h && class[y][x] == weak {
+					class[y][x] = strong
+					stack = append(stack, Point{x, y})
+				}
+			}
+		}
+	}
+
+	pbm := &PBM{newGrid[bool](w, h), w, h, "P1"}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			pbm.data[y][x] = class[y][x] == strong
+		}
+	}
+	return pbm
+}
+
+// Canny returns the edges of the PGM image found with the Canny detector as
+// a PBM image where edges are black. The image is smoothed with a Gaussian
+// of standard deviation sigma, thinned to the maxima of the Sobel gradient
+// magnitude, and pixels above high are kept along with the pixels above low
+// connected to them. Thresholds are fractions of the magnitude of a sharp
+// step from black to white, for example 0.1 and 0.2.
+func (pgm *PGM) Canny(sigma, low, high float64) *PBM {
+	return canny(pgm.plane(false), sigma, low, high)
+}
+
+// Canny returns the edges of the luma of the PPM image, like PGM.Canny.
+func (ppm *PPM) Canny(sigma, low, high float64) *PBM {
+	return ppm.luma().Canny(sigma, low, high)
+}
@@ -0,0 +1,189 @@
+package Netpbm
+
+// Rectangle is an axis-aligned region of an image. Min is inclusive and Max
+// is exclusive, so Rectangle{Point{0, 0}, Point{w, h}} covers a w×h image.
+type Rectangle struct {
+	Min, Max Point
+}
+
+// Rect is shorthand for Rectangle{Point{x0, y0}, Point{x1, y1}}.
+// The coordinates are swapped if needed so that Min is the top-left corner.
+func Rect(x0, y0, x1, y1 int) Rectangle {
+	if x0 > x1 {
+		x0, x1 = x1, x0
+	}
+	if y0 > y1 {
+		y0, y1 = y1, y0
+	}
+	return Rectangle{Point{x0, y0}, Point{x1, y1}}
+}
+
+// Dx returns the width of the rectangle.
+func (r Rectangle) Dx() int {
+	return r.Max.X - r.Min.X
+}
+
+// Dy returns the height of the rectangle.
+func (r Rectangle) Dy() int {
+	return r.Max.Y - r.Min.Y
+}
+
+// Empty reports whether the rectangle contains no pixels.
+func (r Rectangle) Empty() bool {
+	return r.Min.X >= r.Max.X || r.Min.Y >= r.Max.Y
+}
+
+// Intersect returns the largest rectangle contained by both r and s.
+// If they do not overlap, the zero Rectangle is returned.
+func (r Rectangle) Intersect(s Rectangle) Rectangle {
+	if r.Min.X < s.Min.X {
+		r.Min.X = s.Min.X
+	}
+	if r.Min.Y < s.Min.Y {
+		r.Min.Y = s.Min.Y
+	}
+	if r.Max.X > s.Max.X {
+		r.Max.X = s.Max.X
+	}
+	if r.Max.Y > s.Max.Y {
+		r.Max.Y = s.Max.Y
+	}
+	if r.Empty() {
+		return Rectangle{}
+	}
+	return r
+}
+
+// bounds returns the rectangle covering a width×height image.
+func bounds(width, height int) Rectangle {
+	return Rectangle{Point{0, 0}, Point{width, height}}
+}
+
+// newGrid allocates a height×width grid backed by a single slice.
+func newGrid[T any](width, height int) [][]T {
+	buf := make([]T, width*height)
+	grid := make([][]T, height)
+	for y := range grid {
+		grid[y] = buf[y*width : (y+1)*width : (y+1)*width]
+	}
+	return grid
+}
+
+// cloneGrid returns a deep copy of the width×height grid.
+func cloneGrid[T any](data [][]T, width, height int) [][]T {
+	grid := newGrid[T](width, height)
+	for y := 0; y < height; y++ {
+		copy(grid[y], data[y][:width])
+	}
+	return grid
+}
+
+// equalGrid reports whether two width×height grids hold the same values.
+func equalGrid[T comparable](a, b [][]T, width, height int) bool {
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if a[y][x] != b[y][x] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// subGrid returns the rows of data restricted to r. The rows share their
+// backing arrays with data, and their capacity is capped so that appending
+// to them never writes into neighbouring pixels.
+func subGrid[T any](data [][]T, r Rectangle) [][]T {
+	grid := make([][]T, r.Dy())
+	for y := range grid {
+		grid[y] = data[r.Min.Y+y][r.Min.X:r.Max.X:r.Max.X]
+	}
+	return grid
+}
+
+// flopGrid mirrors the grid vertically by swapping row contents, so views
+// created by SubImage see the change in their parent image.
+func flopGrid[T any](data [][]T, height int) {
+	for i := 0; i < height/2; i++ {
+		top, bottom := data[i], data[height-i-1]
+		for x := range top {
+			top[x], bottom[x] = bottom[x], top[x]
+		}
+	}
+}
+
+// Anchor selects the part of an image that stays in place when its canvas
+// is resized.
+type Anchor int
+
+const (
+	AnchorCenter Anchor = iota
+	AnchorTopLeft
+	AnchorTop
+	AnchorTopRight
+	AnchorLeft
+	AnchorRight
+	AnchorBottomLeft
+	AnchorBottom
+	AnchorBottomRight
+)
+
+// offset returns where the top-left corner of a width×height image lands on
+// a newWidth×newHeight canvas.
+func (a Anchor) offset(width, height, newWidth, newHeight int) (int, int) {
+	dx, dy := (newWidth-width)/2, (newHeight-height)/2
+	switch a {
+	case AnchorTopLeft, AnchorLeft, AnchorBottomLeft:
+		dx = 0
+	case AnchorTopRight, AnchorRight, AnchorBottomRight:
+		dx = newWidth - width
+	}
+	switch a {
+	case AnchorTopLeft, AnchorTop, AnchorTopRight:
+		dy = 0
+	case AnchorBottomLeft, AnchorBottom, AnchorBottomRight:
+		dy = newHeight - height
+	}
+	return dx, dy
+}
+
+// canvasSize returns the size of a new canvas, which is empty in both
+// directions when either is zero or negative.
+func canvasSize(width, height int) (int, int) {
+	if width <= 0 || height <= 0 {
+		return 0, 0
+	}
+	return width, height
+}
+
+// reframe copies a width×height grid onto a new newWidth×newHeight grid
+// filled with fill, with the source's top-left corner placed at (dx, dy).
+// Pixels falling outside the new grid are dropped.
+func reframe[T any](data [][]T, width, height, newWidth, newHeight, dx, dy int, fill T) [][]T {
+	newWidth, newHeight = max(newWidth, 0), max(newHeight, 0)
+	grid := newGrid[T](newWidth, newHeight)
+	for y := range grid {
+		sy := y - dy
+		for x := range grid[y] {
+			sx := x - dx
+			if sx >= 0 && sx < width && sy >= 0 && sy < height {
+				grid[y][x] = data[sy][sx]
+			} else {
+				grid[y][x] = fill
+			}
+		}
+	}
+	return grid
+}
+
+// transposeGrid returns a new grid with rows and columns of the width×height
+// grid swapped.
+func transposeGrid[T any](data [][]T, width, height int) [][]T {
+	grid := newGrid[T](height, width)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			grid[x][y] = data[y][x]
+		}
+	}
+	return grid
+}
@@ -0,0 +1,43 @@
+package Netpbm
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestPadAndCanvasResizeToNothing(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	resizes := []struct {
+		name string
+		pgm  func(*PGM)
+		ppm  func(*PPM)
+		pbm  func(*PBM)
+	}{
+		{"Pad right", func(p *PGM) { p.Pad(0, -5, 0, 0, 0) }, func(p *PPM) { p.Pad(0, -5, 0, 0, Pixel{}) }, func(p *PBM) { p.Pad(0, -5, 0, 0, false) }},
+		{"Pad top", func(p *PGM) { p.Pad(-2, 0, -2, 0, 0) }, func(p *PPM) { p.Pad(-2, 0, -2, 0, Pixel{}) }, func(p *PBM) { p.Pad(-2, 0, -2, 0, false) }},
+		{"CanvasResize", func(p *PGM) { p.CanvasResize(0, 4, AnchorCenter, 0) }, func(p *PPM) { p.CanvasResize(6, -1, AnchorCenter, Pixel{}) }, func(p *PBM) { p.CanvasResize(-3, 9, AnchorCenter, false) }},
+	}
+	for _, rs := range resizes {
+		pgm := randomPGM(r, 3, 4, 255)
+		rs.pgm(pgm)
+		if pgm.width != 0 || pgm.height != 0 || len(pgm.data) != 0 {
+			t.Errorf("%s: PGM is %dx%d with %d rows, want empty", rs.name, pgm.width, pgm.height, len(pgm.data))
+		}
+		pgm.Median(1, WindowSquare)
+		pgm.GaussianBlur(1)
+
+		ppm := randomPPM(r, 3, 4, 255)
+		rs.ppm(ppm)
+		if ppm.width != 0 || ppm.height != 0 || len(ppm.data) != 0 {
+			t.Errorf("%s: PPM is %dx%d with %d rows, want empty", rs.name, ppm.width, ppm.height, len(ppm.data))
+		}
+		ppm.Median(1, WindowSquare)
+		ppm.GaussianBlur(1)
+
+		pbm := &PBM{newGrid[bool](3, 4), 3, 4, "P1"}
+		rs.pbm(pbm)
+		if pbm.width != 0 || pbm.height != 0 || len(pbm.data) != 0 {
+			t.Errorf("%s: PBM is %dx%d with %d rows, want empty", rs.name, pbm.width, pbm.height, len(pbm.data))
+		}
+	}
+}
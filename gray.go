@@ -0,0 +1,104 @@
+package Netpbm
+
+import "math"
+
+// Channel names one of the color channels of a Pixel.
+type Channel int
+
+const (
+	Red Channel = iota
+	Green
+	Blue
+)
+
+// of returns the sample of channel c in p.
+func (c Channel) of(p Pixel) uint8 {
+	switch c {
+	case Green:
+		return p.G
+	case Blue:
+		return p.B
+	}
+	return p.R
+}
+
+// GrayMode selects how ToPGMWith turns a color into a gray level.
+type GrayMode int
+
+const (
+	// GrayLuminance decodes sRGB to linear light, computes the relative
+	// luminance with BT.709 primaries and encodes the result again. It keeps
+	// the perceived brightness of saturated colors and is the default.
+	GrayLuminance GrayMode = iota
+	// GrayBT601 computes the BT.601 luma 0.299 R + 0.587 G + 0.114 B on the
+	// encoded samples, like ppmtopgm.
+	GrayBT601
+	// GrayBT709 computes the BT.709 luma 0.2126 R + 0.7152 G + 0.0722 B on
+	// the encoded samples.
+	GrayBT709
+	// GrayChannel extracts the single channel given by GrayConversion.Channel.
+	GrayChannel
+	// GrayDesaturate takes the midpoint of the largest and smallest samples,
+	// the lightness of the HSL model.
+	GrayDesaturate
+	// GrayAverage takes the plain mean of the three samples.
+	GrayAverage
+	// GrayWeights applies GrayConversion.Weights to the encoded samples.
+	GrayWeights
+)
+
+// GrayConversion configures ToPGMWith. The zero value selects GrayLuminance.
+type GrayConversion struct {
+	Mode GrayMode
+	// Channel is the channel extracted by GrayChannel.
+	Channel Channel
+	// Weights are the red, green and blue weights used by GrayWeights. They
+	// are scaled to sum to one; weights summing to zero give a black image.
+	Weights [3]float64
+}
+
+// ToPGMWith converts the PPM image to a PGM image (grayscale) using the
+// conversion selected in conv. The max value is kept.
+func (ppm *PPM) ToPGMWith(conv GrayConversion) *PGM {
+	pgm := &PGM{newGrid[uint8](ppm.width, ppm.height), ppm.width, ppm.height, "P2", ppm.max}
+
+	var weights [3]float64
+	switch conv.Mode {
+	case GrayBT709:
+		weights = [3]float64{0.2126, 0.7152, 0.0722}
+	case GrayWeights:
+		if sum := conv.Weights[0] + conv.Weights[1] + conv.Weights[2]; sum != 0 {
+			for c := range weights {
+				weights[c] = conv.Weights[c] / sum
+			}
+		}
+	}
+	linear := decodeTable(ppm.max, true)
+
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			p := ppm.data[y][x]
+			var gray uint8
+			switch conv.Mode {
+			case GrayBT601:
+				gray = rgbToGray(p)
+			case GrayBT709, GrayWeights:
+				v := weights[0]*float64(p.R) + weights[1]*float64(p.G) + weights[2]*float64(p.B)
+				gray = uint8(math.Round(math.Max(0, math.Min(v, float64(ppm.max)))))
+			case GrayChannel:
+				gray = conv.Channel.of(p)
+			case GrayDesaturate:
+				hi := max(p.R, p.G, p.B)
+				lo := min(p.R, p.G, p.B)
+				gray = uint8((int(hi) + int(lo) + 1) / 2)
+			case GrayAverage:
+				gray = uint8((int(p.R) + int(p.G) + int(p.B) + 1) / 3)
+			default:
+				lum := 0.2126*linear[p.R] + 0.7152*linear[p.G] + 0.0722*linear[p.B]
+				gray = encodeSample(lum, ppm.max, true)
+			}
+			pgm.data[y][x] = gray
+		}
+	}
+	return pgm
+}
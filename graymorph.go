@@ -0,0 +1,289 @@
+package Netpbm
+
+import "fmt"
+
+// morphRun is a horizontal run of members of a structuring element: length
+// pixels starting at offset (dx, dy).
+type morphRun struct {
+	dx, dy, length int
+}
+
+// runs groups the offsets into horizontal runs.
+func runs(offsets []Point) []morphRun {
+	set := make(map[Point]bool, len(offsets))
+	for _, o := range offsets {
+		set[o] = true
+	}
+	var result []morphRun
+	for _, o := range offsets {
+		if set[Point{o.X - 1, o.Y}] {
+			continue
+		}
+		n := 1
+		for set[Point{o.X + n, o.Y}] {
+			n++
+		}
+		result = append(result, morphRun{o.X, o.Y, n})
+	}
+	return result
+}
+
+// slidingExtreme returns the minimum, or the maximum when dilate is set, of
+// every window of the given length over row padded with length-1 pad values
+// on both sides, so that element i covers row[i-length+1 : i+1]. It uses the
+// van Herk/Gil-Werman algorithm, whose cost does not depend on the length.
+func slidingExtreme(row []uint8, length int, dilate bool, pad uint8) []uint8 {
+	pick := func(a, b uint8) uint8 {
+		if dilate {
+			return max(a, b)
+		}
+		return min(a, b)
+	}
+	padded := make([]uint8, len(row)+2*(length-1))
+	for i := range padded {
+		padded[i] = pad
+	}
+	copy(padded[length-1:], row)
+	n := len(padded)
+	prefix, suffix := make([]uint8, n), make([]uint8, n)
+	for i := 0; i < n; i++ {
+		if i%length == 0 {
+			prefix[i] = padded[i]
+		} else {
+			prefix[i] = pick(prefix[i-1], padded[i])
+		}
+	}
+	for i := n - 1; i >= 0; i-- {
+		if i%length == length-1 || i == n-1 {
+			suffix[i] = padded[i]
+		} else {
+			suffix[i] = pick(suffix[i+1], padded[i])
+		}
+	}
+	out := make([]uint8, n-length+1)
+	for i := range out {
+		out[i] = pick(suffix[i], prefix[i+length-1])
+	}
+	return out
+}
+
+// grayMorph returns the grid eroded by the offsets, or dilated by them when
+// dilate is set. Pixels outside the image do not take part.
+func grayMorph(data [][]uint8, width, height int, maxValue uint8, offsets []Point, dilate bool) [][]uint8 {
+	pad := maxValue
+	if dilate {
+		pad = 0
+		reflected := make([]Point, len(offsets))
+		for i, o := range offsets {
+			reflected[i] = Point{-o.X, -o.Y}
+		}
+		offsets = reflected
+	}
+	out := newGrid[uint8](width, height)
+	for y := range out {
+		for x := range out[y] {
+			out[y][x] = pad
+		}
+	}
+	rs := runs(offsets)
+
+	// Windows of every run length are computed once per row.
+	windows := make(map[int][][]uint8)
+	for _, r := range rs {
+		if windows[r.length] != nil {
+			continue
+		}
+		rows := make([][]uint8, height)
+		for y := range rows {
+			rows[y] = slidingExtreme(data[y], r.length, dilate, pad)
+		}
+		windows[r.length] = rows
+	}
+
+	for _, r := range rs {
+		rows := windows[r.length]
+		for y := 0; y < height; y++ {
+			sy := y + r.dy
+			if sy < 0 || sy >= height {
+				continue
+			}
+			src, dst := rows[sy], out[y]
+			for x := 0; x < width; x++ {
+				// The run covers columns x+dx to x+dx+length-1, which is the
+				// window ending at that last column.
+				i := x + r.dx + r.length - 1
+				if i < 0 || i >= len(src) {
+					continue
+				}
+				if dilate {
+					dst[x] = max(dst[x], src[i])
+				} else {
+					dst[x] = min(dst[x], src[i])
+				}
+			}
+		}
+	}
+	return out
+}
+
+// morph returns the samples of the PGM image eroded or dilated by se.
+func (pgm *PGM) morph(se StructuringElement, dilate bool) [][]uint8 {
+	return grayMorph(pgm.data, pgm.width, pgm.height, pgm.max, se.members(), dilate)
+}
+
+// setGrid copies the samples of data into the PGM image in place.
+func (pgm *PGM) setGrid(data [][]uint8) {
+	for y := range data {
+		copy(pgm.data[y], data[y])
+	}
+}
+
+// opened returns the samples of the PGM image opened by se.
+func (pgm *PGM) opened(se StructuringElement) [][]uint8 {
+	eroded := &PGM{pgm.morph(se, false), pgm.width, pgm.height, pgm.magicNumber, pgm.max}
+	return eroded.morph(se, true)
+}
+
+// closed returns the samples of the PGM image closed by se.
+func (pgm *PGM) closed(se StructuringElement) [][]uint8 {
+	dilated := &PGM{pgm.morph(se, true), pgm.width, pgm.height, pgm.magicNumber, pgm.max}
+	return dilated.morph(se, false)
+}
+
+// Erode replaces every sample of the PGM image with the darkest sample under
+// the flat structuring element placed on it. Pixels outside the image are
+// ignored. Runs of the element are processed with the van Herk/Gil-Werman
+// algorithm, so large elements stay fast.
+func (pgm *PGM) Erode(se StructuringElement) {
+	pgm.setGrid(pgm.morph(se, false))
+}
+
+// Dilate replaces every sample of the PGM image with the brightest sample
+// under the flat structuring element, reflected about its origin, placed on
+// it. Pixels outside the image are ignored.
+func (pgm *PGM) Dilate(se StructuringElement) {
+	pgm.setGrid(pgm.morph(se, true))
+}
+
+// Open erodes then dilates the PGM image, removing bright details smaller
+// than the structuring element.
+func (pgm *PGM) Open(se StructuringElement) {
+	pgm.setGrid(pgm.opened(se))
+}
+
+// Close dilates then erodes the PGM image, removing dark details smaller
+// than the structuring element.
+func (pgm *PGM) Close(se StructuringElement) {
+	pgm.setGrid(pgm.closed(se))
+}
+
+// difference returns a - b, or zero when b is larger, which happens when the
+// structuring element leaves out its origin.
+func difference(a, b uint8) uint8 {
+	return uint8(max(int(a)-int(b), 0))
+}
+
+// MorphGradient replaces the PGM image with the difference between its
+// dilation and its erosion by the structuring element, which highlights
+// edges.
+func (pgm *PGM) MorphGradient(se StructuringElement) {
+	dilated, eroded := pgm.morph(se, true), pgm.morph(se, false)
+	for y := range dilated {
+		for x := range dilated[y] {
+			pgm.data[y][x] = difference(dilated[y][x], eroded[y][x])
+		}
+	}
+}
+
+// TopHat replaces the PGM image with its difference from its opening by the
+// structuring element, which keeps bright details smaller than the element
+// and removes an uneven background.
+func (pgm *PGM) TopHat(se StructuringElement) {
+	opened := pgm.opened(se)
+	for y := range opened {
+		for x := range opened[y] {
+			pgm.data[y][x] = difference(pgm.data[y][x], opened[y][x])
+		}
+	}
+}
+
+// BlackTopHat replaces the PGM image with the difference between its closing
+// by the structuring element and itself, which keeps dark details smaller
+// than the element as bright ones.
+func (pgm *PGM) BlackTopHat(se StructuringElement) {
+	closed := pgm.closed(se)
+	for y := range closed {
+		for x := range closed[y] {
+			pgm.data[y][x] = difference(closed[y][x], pgm.data[y][x])
+		}
+	}
+}
+
+// ReconstructByDilation treats the PGM image as a marker and dilates it
+// repeatedly, 8-connected, while keeping it below mask, until it no longer
+// changes. Bright regions of mask that the marker touches are restored
+// entirely while the others stay at the marker level. The mask must have the
+// same size as the image.
+func (pgm *PGM) ReconstructByDilation(mask *PGM) error {
+	if mask.width != pgm.width || mask.height != pgm.height {
+		return fmt.Errorf("mask size mismatch: %dx%d and %dx%d", pgm.width, pgm.height, mask.width, mask.height)
+	}
+	w, h := pgm.width, pgm.height
+	m := mask.data
+	v := pgm.data
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v[y][x] = min(v[y][x], m[y][x])
+		}
+	}
+
+	// Vincent's hybrid algorithm: a forward and a backward raster scan, then
+	// a queue to propagate the remaining changes.
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			best := v[y][x]
+			for _, d := range [4]Point{{-1, -1}, {0, -1}, {1, -1}, {-1, 0}} {
+				if nx, ny := x+d.X, y+d.Y; nx >= 0 && ny >= 0 && nx < w {
+					best = max(best, v[ny][nx])
+				}
+			}
+			v[y][x] = min(best, m[y][x])
+		}
+	}
+	var queue []Point
+	for y := h - 1; y >= 0; y-- {
+		for x := w - 1; x >= 0; x-- {
+			best := v[y][x]
+			backward := [4]Point{{1, 1}, {0, 1}, {-1, 1}, {1, 0}}
+			for _, d := range backward {
+				if nx, ny := x+d.X, y+d.Y; nx >= 0 && nx < w && ny < h {
+					best = max(best, v[ny][nx])
+				}
+			}
+			v[y][x] = min(best, m[y][x])
+			for _, d := range backward {
+				if nx, ny := x+d.X, y+d.Y; nx >= 0 && nx < w && ny < h && v[ny][nx] < v[y][x] && v[ny][nx] < m[ny][nx] {
+					queue = append(queue, Point{x, y})
+					break
+				}
+			}
+		}
+	}
+	for len(queue) > 0 {
+		q := queue[0]
+		queue = queue[1:]
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				nx, ny := q.X+dx, q.Y+dy
+				if nx < 0 || ny < 0 || nx >= w || ny >= h || (dx == 0 && dy == 0) {
+					continue
+				}
+				if v[ny][nx] < v[q.Y][q.X] && v[ny][nx] != m[ny][nx] {
+					v[ny][nx] = min(v[q.Y][q.X], m[ny][nx])
+					queue = append(queue, Point{nx, ny})
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package Netpbm
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestGrayMorphDifferencesDoNotWrap(t *testing.T) {
+	// Neither element contains its origin, so the erosion can be brighter
+	// than the dilation and the opening brighter than the image.
+	right := &PBM{newGrid[bool](3, 1), 3, 1, "P1"}
+	right.data[0][2] = true
+	elements := []StructuringElement{{right, Point{1, 0}}, {}}
+
+	r := rand.New(rand.NewSource(1))
+	img := randomPGM(r, 10, 6, 255)
+	apply := func(op func(*PGM, StructuringElement), se StructuringElement) *PGM {
+		out := img.Clone()
+		op(out, se)
+		return out
+	}
+	for i, se := range elements {
+		tests := []struct {
+			name string
+			op   func(*PGM, StructuringElement)
+			a, b *PGM
+		}{
+			{"MorphGradient", (*PGM).MorphGradient, apply((*PGM).Dilate, se), apply((*PGM).Erode, se)},
+			{"TopHat", (*PGM).TopHat, img, apply((*PGM).Open, se)},
+			{"BlackTopHat", (*PGM).BlackTopHat, apply((*PGM).Close, se), img},
+		}
+		for _, tt := range tests {
+			got := apply(tt.op, se)
+			for y := range got.data {
+				for x, v := range got.data[y] {
+					if want := max(int(tt.a.data[y][x])-int(tt.b.data[y][x]), 0); int(v) != want {
+						t.Fatalf("%s with element %d: sample (%d, %d) is %d, want %d", tt.name, i, x, y, v, want)
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package Netpbm
+
+import "math"
+
+// Histogram counts the pixels of the PGM image at each level. The result has
+// max+1 entries; samples above max are counted as max.
+func (pgm *PGM) Histogram() []int {
+	hist := make([]int, int(pgm.max)+1)
+	for y := 0; y < pgm.height; y++ {
+		for x := 0; x < pgm.width; x++ {
+			hist[min(pgm.data[y][x], pgm.max)]++
+		}
+	}
+	return hist
+}
+
+// Histogram counts the pixels of the PPM image at each level, separately for
+// the red, green and blue channels. Each result has max+1 entries.
+func (ppm *PPM) Histogram() (r, g, b []int) {
+	r = make([]int, int(ppm.max)+1)
+	g = make([]int, int(ppm.max)+1)
+	b = make([]int, int(ppm.max)+1)
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			p := ppm.data[y][x]
+			r[min(p.R, ppm.max)]++
+			g[min(p.G, ppm.max)]++
+			b[min(p.B, ppm.max)]++
+		}
+	}
+	return r, g, b
+}
+
+// Cumulative returns the cumulative distribution of a histogram: entry v
+// holds the number of samples at or below level v.
+func Cumulative(hist []int) []int {
+	cdf := make([]int, len(hist))
+	sum := 0
+	for v, n := range hist {
+		sum += n
+		cdf[v] = sum
+	}
+	return cdf
+}
+
+// equalizeTable returns the lookup table that spreads the levels of hist
+// evenly over [0, max].
+func equalizeTable(hist []int, max uint8) []uint8 {
+	cdf := Cumulative(hist)
+	total := cdf[len(cdf)-1]
+	first := 0
+	for _, c := range cdf {
+		if c > 0 {
+			first = c
+			break
+		}
+	}
+	table := make([]uint8, 256)
+	for v := range table {
+		if v >= len(cdf) || total == first {
+			table[v] = uint8(min(v, int(max)))
+			continue
+		}
+		f := float64(cdf[v]-first) / float64(total-first)
+		table[v] = toSample(f, max)
+	}
+	return table
+}
+
+// matchTable returns the lookup table that gives a histogram src the shape
+// of ref. Levels of ref are rescaled from refMax to max.
+func matchTable(src, ref []int, max, refMax uint8) []uint8 {
+	srcCDF, refCDF := Cumulative(src), Cumulative(ref)
+	srcTotal, refTotal := float64(srcCDF[len(srcCDF)-1]), float64(refCDF[len(refCDF)-1])
+	table := make([]uint8, 256)
+	if srcTotal == 0 || refTotal == 0 {
+		for v := range table {
+			table[v] = uint8(min(v, int(max)))
+		}
+		return table
+	}
+	r := 0
+	for v := range table {
+		if v >= len(srcCDF) {
+			table[v] = table[v-1]
+			continue
+		}
+		f := float64(srcCDF[v]) / srcTotal
+		for r < len(refCDF)-1 && float64(refCDF[r])/refTotal < f {
+			r++
+		}
+		table[v] = toSample(float64(r)/math.Max(float64(refMax), 1), max)
+	}
+	return table
+}
+
+// valueHistogram counts the pixels of the PPM image at each HSV value, the
+// largest of the three samples.
+func (ppm *PPM) valueHistogram() []int {
+	hist := make([]int, int(ppm.max)+1)
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			p := ppm.data[y][x]
+			hist[min(max(p.R, p.G, p.B), ppm.max)]++
+		}
+	}
+	return hist
+}
+
+// setValue scales the pixel at (x, y) so that its HSV value becomes v,
+// keeping hue and saturation.
+func (ppm *PPM) setValue(x, y int, v uint8) {
+	p := ppm.data[y][x]
+	old := max(p.R, p.G, p.B)
+	if old == 0 {
+		ppm.data[y][x] = Pixel{v, v, v}
+		return
+	}
+	f := float64(v) / float64(old)
+	scale := func(s uint8) uint8 {
+		return uint8(math.Min(math.Round(float64(s)*f), float64(ppm.max)))
+	}
+	ppm.data[y][x] = Pixel{scale(p.R), scale(p.G), scale(p.B)}
+}
+
+// Equalize spreads the levels of the PGM image so that they are used evenly,
+// like pnmhisteq.
+func (pgm *PGM) Equalize() {
+	pgm.mapSamples(equalizeTable(pgm.Histogram(), pgm.max))
+}
+
+// Equalize spreads the HSV values of the PPM image so that they are used
+// evenly, keeping hue and saturation, like pnmhisteq.
+func (ppm *PPM) Equalize() {
+	table := equalizeTable(ppm.valueHistogram(), ppm.max)
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			p := ppm.data[y][x]
+			ppm.setValue(x, y, table[max(p.R, p.G, p.B)])
+		}
+	}
+}
+
+// MatchHistogram remaps the levels of the PGM image so that its histogram
+// takes the shape of the histogram of ref.
+func (pgm *PGM) MatchHistogram(ref *PGM) {
+	pgm.mapSamples(matchTable(pgm.Histogram(), ref.Histogram(), pgm.max, ref.max))
+}
+
+// MatchHistogram remaps each channel of the PPM image so that its histogram
+// takes the shape of the same channel of ref.
+func (ppm *PPM) MatchHistogram(ref *PPM) {
+	r, g, b := ppm.Histogram()
+	refR, refG, refB := ref.Histogram()
+	tr := matchTable(r, refR, ppm.max, ref.max)
+	tg := matchTable(g, refG, ppm.max, ref.max)
+	tb := matchTable(b, refB, ppm.max, ref.max)
+	ppm.mapPixels(func(p Pixel) Pixel {
+		return Pixel{tr[p.R], tg[p.G], tb[p.B]}
+	})
+}
+
+// clahe computes contrast-limited adaptive histogram equalization of a
+// width×height grid of levels in [0, max] read through level. It returns the
+// equalized level of every pixel in row-major order.
+func clahe(width, height int, max uint8, level func(x, y int) uint8, tilesX, tilesY int, clipLimit float64) []uint8 {
+	if tilesX <= 0 {
+		tilesX = 8
+	}
+	if tilesY <= 0 {
+		tilesY = 8
+	}
+	tilesX, tilesY = min(tilesX, width), min(tilesY, height)
+	// Tile t covers [t*size/tiles, (t+1)*size/tiles) so that the tiles split
+	// the image exactly even when the size is not a multiple of their count.
+	edge := func(t, size, tiles int) int { return t * size / tiles }
+	bins := int(max) + 1
+
+	// Build the clipped equalization table of every tile.
+	tables := make([][]float64, tilesX*tilesY)
+	for ty := 0; ty < tilesY; ty++ {
+		for tx := 0; tx < tilesX; tx++ {
+			hist := make([]float64, bins)
+			x0, y0 := edge(tx, width, tilesX), edge(ty, height, tilesY)
+			x1, y1 := edge(tx+1, width, tilesX), edge(ty+1, height, tilesY)
+			area := float64((x1 - x0) * (y1 - y0))
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					hist[level(x, y)]++
+				}
+			}
+			if clipLimit > 0 {
+				// Clip the peaks and spread the excess over every bin.
+				limit := math.Max(clipLimit*area/float64(bins), 1)
+				excess := 0.0
+				for v := range hist {
+					if hist[v] > limit {
+						excess += hist[v] - limit
+						hist[v] = limit
+					}
+				}
+				for v := range hist {
+					hist[v] += excess / float64(bins)
+				}
+			}
+			table := make([]float64, bins)
+			sum := 0.0
+			for v := range hist {
+				sum += hist[v]
+				if area > 0 {
+					table[v] = sum / area * float64(max)
+				}
+			}
+			tables[ty*tilesX+tx] = table
+		}
+	}
+
+	// Interpolate bilinearly between the tables of the four nearest tiles.
+	// coord returns the two tiles whose centers surround position pos and the
+	// weight of the second one.
+	coord := func(pos, size, tiles int) (int, int, float64) {
+		center := func(t int) float64 {
+			return float64(edge(t, size, tiles)+edge(t+1, size, tiles)) / 2
+		}
+		p := float64(pos) + 0.5
+		if p <= center(0) {
+			return 0, 0, 0
+		}
+		for t := 0; t < tiles-1; t++ {
+			if c0, c1 := center(t), center(t+1); p < c1 {
+				return t, t + 1, (p - c0) / (c1 - c0)
+			}
+		}
+		return tiles - 1, tiles - 1, 0
+	}
+	out := make([]uint8, width*height)
+	for y := 0; y < height; y++ {
+		ty0, ty1, fy := coord(y, height, tilesY)
+		for x := 0; x < width; x++ {
+			tx0, tx1, fx := coord(x, width, tilesX)
+			v := level(x, y)
+			top := tables[ty0*tilesX+tx0][v]*(1-fx) + tables[ty0*tilesX+tx1][v]*fx
+			bottom := tables[ty1*tilesX+tx0][v]*(1-fx) + tables[ty1*tilesX+tx1][v]*fx
+			out[y*width+x] = uint8(math.Round(math.Min(top*(1-fy)+bottom*fy, float64(max))))
+		}
+	}
+	return out
+}
+
+// CLAHE applies contrast-limited adaptive histogram equalization to the PGM
+// image. The image is divided into tilesX×tilesY tiles (zero selects 8) that
+// are equalized separately and blended together. clipLimit caps every
+// histogram bin at that multiple of the average bin height before
+// equalizing, which limits noise amplification; zero or less disables it.
+func (pgm *PGM) CLAHE(tilesX, tilesY int, clipLimit float64) {
+	if pgm.width <= 0 || pgm.height <= 0 {
+		return
+	}
+	level := func(x, y int) uint8 {
+		return min(pgm.data[y][x], pgm.max)
+	}
+	out := clahe(pgm.width, pgm.height, pgm.max, level, tilesX, tilesY, clipLimit)
+	for y := 0; y < pgm.height; y++ {
+		copy(pgm.data[y], out[y*pgm.width:(y+1)*pgm.width])
+	}
+}
+
+// CLAHE applies contrast-limited adaptive histogram equalization to the HSV
+// values of the PPM image, keeping hue and saturation. The parameters are
+// those of PGM.CLAHE.
+func (ppm *PPM) CLAHE(tilesX, tilesY int, clipLimit float64) {
+	if ppm.width <= 0 || ppm.height <= 0 {
+		return
+	}
+	level := func(x, y int) uint8 {
+		p := ppm.data[y][x]
+		return min(max(p.R, p.G, p.B), ppm.max)
+	}
+	out := clahe(ppm.width, ppm.height, ppm.max, level, tilesX, tilesY, clipLimit)
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			ppm.setValue(x, y, out[y*ppm.width+x])
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package Netpbm
+
+import "testing"
+
+func flatPGM(width, height int, level, max uint8) *PGM {
+	pgm := &PGM{newGrid[uint8](width, height), width, height, "P2", max}
+	for y := range pgm.data {
+		for x := range pgm.data[y] {
+			pgm.data[y][x] = level
+		}
+	}
+	return pgm
+}
+
+func TestCLAHEUnevenTiles(t *testing.T) {
+	tests := []struct {
+		width, height, tilesX, tilesY int
+		clipLimit                     float64
+	}{
+		{10, 10, 0, 0, 0},
+		{10, 10, 0, 0, 2},
+		{13, 7, 4, 3, 0},
+		{37, 21, 5, 8, 3},
+	}
+	for _, tt := range tests {
+		pgm := flatPGM(tt.width, tt.height, 200, 255)
+		pgm.CLAHE(tt.tilesX, tt.tilesY, tt.clipLimit)
+		// Equalizing a flat image maps its level to at least itself, and to
+		// the max value without clipping. Tiles past the image edge used to
+		// pull the border toward black.
+		for y := range pgm.data {
+			for x, v := range pgm.data[y] {
+				if v < 200 || tt.clipLimit == 0 && v != 255 {
+					t.Fatalf("%dx%d with %dx%d tiles and clip limit %g: pixel (%d, %d) is %d",
+						tt.width, tt.height, tt.tilesX, tt.tilesY, tt.clipLimit, x, y, v)
+				}
+			}
+		}
+	}
+}
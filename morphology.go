@@ -0,0 +1,274 @@
+package Netpbm
+
+// StructuringElement is the neighborhood used by the morphological
+// operations. Its members are the black pixels of Pattern, taken relative to
+// Origin, a position inside the pattern.
+type StructuringElement struct {
+	Pattern *PBM
+	Origin  Point
+}
+
+// members returns the offsets of the black pixels of the element from its
+// origin.
+func (se StructuringElement) members() []Point {
+	if se.Pattern == nil {
+		return nil
+	}
+	var offsets []Point
+	for y := 0; y < se.Pattern.height; y++ {
+		for x := 0; x < se.Pattern.width; x++ {
+			if se.Pattern.data[y][x] {
+				offsets = append(offsets, Point{x - se.Origin.X, y - se.Origin.Y})
+			}
+		}
+	}
+	return offsets
+}
+
+// element builds a structuring element of the given size centered on its
+// middle pixel, with the pixels for which member returns true.
+func element(width, height int, member func(x, y int) bool) StructuringElement {
+	pattern := &PBM{newGrid[bool](width, height), width, height, "P1"}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			pattern.data[y][x] = member(x-width/2, y-height/2)
+		}
+	}
+	return StructuringElement{pattern, Point{width / 2, height / 2}}
+}
+
+// RectElement returns a structuring element filling a width×height
+// rectangle, with its origin at the middle. A width or height of one gives
+// a line.
+func RectElement(width, height int) StructuringElement {
+	return element(max(width, 1), max(height, 1), func(x, y int) bool { return true })
+}
+
+// DiskElement returns a structuring element holding the pixels whose
+// distance to its center is at most radius.
+func DiskElement(radius int) StructuringElement {
+	size := 2*max(radius, 0) + 1
+	return element(size, size, func(x, y int) bool { return x*x+y*y <= radius*radius })
+}
+
+// CrossElement returns a structuring element shaped like a plus sign whose
+// arms are radius pixels long.
+func CrossElement(radius int) StructuringElement {
+	size := 2*max(radius, 0) + 1
+	return element(size, size, func(x, y int) bool { return x == 0 || y == 0 })
+}
+
+// bitmap is a bilevel image packed 64 pixels per word, with pixel x of a row
+// at bit x%64 of word x/64. Bits past the width are kept clear.
+type bitmap struct {
+	width, height, words int
+	rows                 [][]uint64
+}
+
+func newBitmap(width, height int) *bitmap {
+	words := (width + 63) / 64
+	b := &bitmap{width, height, words, make([][]uint64, height)}
+	pix := make([]uint64, words*height)
+	for y := range b.rows {
+		b.rows[y] = pix[y*words : (y+1)*words : (y+1)*words]
+	}
+	return b
+}
+
+// setBits sets the bits of row in [from, to).
+func setBits(row []uint64, from, to int) {
+	for x := max(from, 0); x < to; {
+		if x%64 == 0 && to-x >= 64 {
+			row[x/64] = ^uint64(0)
+			x += 64
+			continue
+		}
+		row[x/64] |= 1 << (x % 64)
+		x++
+	}
+}
+
+// trim clears the bits of row past the width.
+func (b *bitmap) trim(row []uint64) {
+	if r := b.width % 64; r != 0 && b.words > 0 {
+		row[b.words-1] &= 1<<r - 1
+	}
+}
+
+// shifted stores in dst the pixels of row y+dy of b starting at column dx,
+// so that bit x of dst is the pixel (x+dx, y+dy). Pixels outside the image
+// read as fill.
+func (b *bitmap) shifted(dst []uint64, y, dx, dy int, fill bool) {
+	sy := y + dy
+	if sy < 0 || sy >= b.height {
+		for i := range dst {
+			dst[i] = 0
+		}
+		if fill {
+			setBits(dst, 0, b.width)
+		}
+		return
+	}
+	src := b.rows[sy]
+	word := func(i int) uint64 {
+		if i < 0 || i >= b.words {
+			return 0
+		}
+		return src[i]
+	}
+	ws, bs := dx/64, dx%64
+	if bs < 0 {
+		ws, bs = ws-1, bs+64
+	}
+	for i := range dst {
+		w := word(i+ws) >> bs
+		if bs != 0 {
+			w |= word(i+ws+1) << (64 - bs)
+		}
+		dst[i] = w
+	}
+	if fill {
+		if dx > 0 {
+			setBits(dst, b.width-dx, b.width)
+		} else if dx < 0 {
+			setBits(dst, 0, min(-dx, b.width))
+		}
+	}
+	b.trim(dst)
+}
+
+// bitmap packs the PBM image, with black pixels as set bits.
+func (pbm *PBM) bitmap() *bitmap {
+	b := newBitmap(pbm.width, pbm.height)
+	for y := 0; y < pbm.height; y++ {
+		for x := 0; x < pbm.width; x++ {
+			if pbm.data[y][x] {
+				b.rows[y][x/64] |= 1 << (x % 64)
+			}
+		}
+	}
+	return b
+}
+
+// setBitmap stores b back into the PBM image in place.
+func (pbm *PBM) setBitmap(b *bitmap) {
+	for y := 0; y < pbm.height; y++ {
+		for x := 0; x < pbm.width; x++ {
+			pbm.data[y][x] = b.rows[y][x/64]>>(x%64)&1 != 0
+		}
+	}
+}
+
+// dilate returns b dilated by the offsets: a pixel is set when any pixel at
+// minus an offset from it is set.
+func (b *bitmap) dilate(offsets []Point) *bitmap {
+	out := newBitmap(b.width, b.height)
+	tmp := make([]uint64, b.words)
+	for y := 0; y < b.height; y++ {
+		for _, o := range offsets {
+			b.shifted(tmp, y, -o.X, -o.Y, false)
+			for i, w := range tmp {
+				out.rows[y][i] |= w
+			}
+		}
+	}
+	return out
+}
+
+// erode returns b eroded by the offsets: a pixel is set when every pixel at
+// an offset from it is set, reading pixels outside the image as outside.
+func (b *bitmap) erode(offsets []Point, outside bool) *bitmap {
+	out := newBitmap(b.width, b.height)
+	tmp := make([]uint64, b.words)
+	for y := 0; y < b.height; y++ {
+		row := out.rows[y]
+		setBits(row, 0, b.width)
+		for _, o := range offsets {
+			b.shifted(tmp, y, o.X, o.Y, outside)
+			for i, w := range tmp {
+				row[i] &= w
+			}
+		}
+	}
+	return out
+}
+
+// combine returns the bitmap whose words are op applied to those of a and b.
+func combine(a, b *bitmap, op func(x, y uint64) uint64) *bitmap {
+	out := newBitmap(a.width, a.height)
+	for y := range out.rows {
+		for i := range out.rows[y] {
+			out.rows[y][i] = op(a.rows[y][i], b.rows[y][i])
+		}
+		out.trim(out.rows[y])
+	}
+	return out
+}
+
+func (b *bitmap) open(offsets []Point) *bitmap {
+	return b.erode(offsets, false).dilate(offsets)
+}
+
+// close dilates then erodes b. The erosion reads pixels outside the image as
+// set, since the dilation would have spread black past the border too, so
+// closing never clears a pixel.
+func (b *bitmap) close(offsets []Point) *bitmap {
+	return b.dilate(offsets).erode(offsets, true)
+}
+
+// Dilate grows the black regions of the PBM image: a pixel becomes black
+// when the structuring element, reflected about its origin and placed on it,
+// covers a black pixel.
+func (pbm *PBM) Dilate(se StructuringElement) {
+	pbm.setBitmap(pbm.bitmap().dilate(se.members()))
+}
+
+// Erode shrinks the black regions of the PBM image: a pixel stays black only
+// when the structuring element placed on it covers black pixels only.
+// Pixels outside the image count as white.
+func (pbm *PBM) Erode(se StructuringElement) {
+	pbm.setBitmap(pbm.bitmap().erode(se.members(), false))
+}
+
+// Open erodes then dilates the PBM image, removing black details smaller
+// than the structuring element.
+func (pbm *PBM) Open(se StructuringElement) {
+	pbm.setBitmap(pbm.bitmap().open(se.members()))
+}
+
+// Close dilates then erodes the PBM image, filling white gaps and holes
+// smaller than the structuring element.
+func (pbm *PBM) Close(se StructuringElement) {
+	pbm.setBitmap(pbm.bitmap().close(se.members()))
+}
+
+// TopHat keeps the black details of the PBM image that an opening with the
+// structuring element removes.
+func (pbm *PBM) TopHat(se StructuringElement) {
+	b := pbm.bitmap()
+	pbm.setBitmap(combine(b, b.open(se.members()), func(x, y uint64) uint64 { return x &^ y }))
+}
+
+// BlackTopHat keeps the white details of the PBM image that a closing with
+// the structuring element fills, drawn in black.
+func (pbm *PBM) BlackTopHat(se StructuringElement) {
+	b := pbm.bitmap()
+	pbm.setBitmap(combine(b.close(se.members()), b, func(x, y uint64) uint64 { return x &^ y }))
+}
+
+// HitOrMiss turns black the pixels of the PBM image where every member of
+// hit lies on a black pixel and every member of miss on a white one, and
+// white all others, which finds a given local pattern. Pixels outside the
+// image count as white.
+func (pbm *PBM) HitOrMiss(hit, miss StructuringElement) {
+	b := pbm.bitmap()
+	inverse := combine(b, b, func(x, _ uint64) uint64 { return ^x })
+	pbm.setBitmap(combine(b.erode(hit.members(), false), inverse.erode(miss.members(), true), func(x, y uint64) uint64 { return x & y }))
+}
+
+// Boundary keeps the black pixels of the PBM image that an erosion with the
+// structuring element removes, which outlines every black region.
+func (pbm *PBM) Boundary(se StructuringElement) {
+	b := pbm.bitmap()
+	pbm.setBitmap(combine(b, b.erode(se.members(), false), func(x, y uint64) uint64 { return x &^ y }))
+}
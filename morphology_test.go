@@ -0,0 +1,61 @@
+package Netpbm
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func randomPBM(r *rand.Rand, width, height int, density float64) *PBM {
+	pbm := &PBM{newGrid[bool](width, height), width, height, "P1"}
+	for y := range pbm.data {
+		for x := range pbm.data[y] {
+			pbm.data[y][x] = r.Float64() < density
+		}
+	}
+	return pbm
+}
+
+func TestCloseKeepsBlackPixels(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	elements := []StructuringElement{RectElement(3, 3), RectElement(5, 1), DiskElement(2), CrossElement(1)}
+
+	full := &PBM{newGrid[bool](5, 5), 5, 5, "P1"}
+	corner := &PBM{newGrid[bool](70, 6), 70, 6, "P1"}
+	for y := range full.data {
+		for x := range full.data[y] {
+			full.data[y][x] = true
+		}
+	}
+	corner.data[0][69] = true
+	images := []*PBM{full, corner, randomPBM(r, 70, 13, 0.3), randomPBM(r, 9, 9, 0.7)}
+
+	for i, img := range images {
+		for j, se := range elements {
+			closed := img.Clone()
+			closed.Close(se)
+			for y := range img.data {
+				for x, black := range img.data[y] {
+					if black && !closed.data[y][x] {
+						t.Fatalf("image %d element %d: Close cleared pixel (%d, %d)", i, j, x, y)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestOpenKeepsOnlyBlackPixels(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	for _, se := range []StructuringElement{RectElement(3, 3), DiskElement(2), CrossElement(1)} {
+		img := randomPBM(r, 70, 13, 0.6)
+		opened := img.Clone()
+		opened.Open(se)
+		for y := range img.data {
+			for x, black := range opened.data[y] {
+				if black && !img.data[y][x] {
+					t.Fatalf("Open set white pixel (%d, %d)", x, y)
+				}
+			}
+		}
+	}
+}
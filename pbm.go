@@ -214,9 +214,60 @@ func (pbm *PBM) Flip() {
 
 // Flop flops the PBM image vertically.
 func (pbm *PBM) Flop() {
-	for i := 0; i < pbm.height/2; i++ {
-		pbm.data[i], pbm.data[pbm.height-i-1] = pbm.data[pbm.height-i-1], pbm.data[i]
+	flopGrid(pbm.data, pbm.height)
+}
+
+// Clone returns a deep copy of the PBM image.
+func (pbm *PBM) Clone() *PBM {
+	return &PBM{cloneGrid(pbm.data, pbm.width, pbm.height), pbm.width, pbm.height, pbm.magicNumber}
+}
+
+// Equal reports whether both PBM images have the same dimensions and pixels.
+func (pbm *PBM) Equal(other *PBM) bool {
+	if pbm == nil || other == nil {
+		return pbm == other
 	}
+	if pbm.width != other.width || pbm.height != other.height {
+		return false
+	}
+	return equalGrid(pbm.data, other.data, pbm.width, pbm.height)
+}
+
+// SubImage returns a view of the part of the image inside r. The view shares
+// its pixels with the original image, so changes made through one are visible
+// in the other. r is clipped to the image bounds.
+func (pbm *PBM) SubImage(r Rectangle) *PBM {
+	r = r.Intersect(bounds(pbm.width, pbm.height))
+	return &PBM{subGrid(pbm.data, r), r.Dx(), r.Dy(), pbm.magicNumber}
+}
+
+// Crop keeps only the part of the PBM image inside r, like pnmcut.
+// r is clipped to the image bounds.
+func (pbm *PBM) Crop(r Rectangle) {
+	r = r.Intersect(bounds(pbm.width, pbm.height))
+	pbm.data = reframe(pbm.data, pbm.width, pbm.height, r.Dx(), r.Dy(), -r.Min.X, -r.Min.Y, false)
+	pbm.width, pbm.height = r.Dx(), r.Dy()
+}
+
+// Pad adds borders of the given widths around the PBM image, filled with
+// fill, like pnmpad. A negative width removes pixels from that side instead;
+// when no column or no row is left, the image becomes 0×0.
+func (pbm *PBM) Pad(top, right, bottom, left int, fill bool) {
+	width, height := canvasSize(pbm.width+left+right, pbm.height+top+bottom)
+	pbm.data = reframe(pbm.data, pbm.width, pbm.height, width, height, left, top, fill)
+	pbm.width, pbm.height = width, height
+}
+
+// CanvasResize changes the size of the PBM canvas to width×height without
+// scaling the image. The anchor decides which part of the image stays in
+// place; the image is cropped where the canvas shrinks and new pixels are set
+// to fill where it grows. A width or height of zero or less gives a 0×0
+// image.
+func (pbm *PBM) CanvasResize(width, height int, anchor Anchor, fill bool) {
+	width, height = canvasSize(width, height)
+	dx, dy := anchor.offset(pbm.width, pbm.height, width, height)
+	pbm.data = reframe(pbm.data, pbm.width, pbm.height, width, height, dx, dy, fill)
+	pbm.width, pbm.height = width, height
 }
 
 // SetMagicNumber sets the magic number of the PBM image.
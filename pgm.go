@@ -221,13 +221,9 @@ func saveP5PGM(file *bufio.Writer, pgm *PGM) error {
 	return nil
 }
 
-// Invert inverts the colors of the PGM image.
+// Invert inverts the colors of the PGM image relative to its max value.
 func (pgm *PGM) Invert() {
-	for i := range pgm.data {
-		for j := range pgm.data[i] {
-			pgm.data[i][j] = uint8(pgm.max) - pgm.data[i][j]
-		}
-	}
+	pgm.mapSamples(invertTable(pgm.max))
 }
 
 // Flip flips the PGM image horizontally.
@@ -241,9 +237,60 @@ func (pgm *PGM) Flip() {
 
 // Flop flops the PGM image vertically.
 func (pgm *PGM) Flop() {
-	for i := 0; i < pgm.height/2; i++ {
-		pgm.data[i], pgm.data[pgm.height-i-1] = pgm.data[pgm.height-i-1], pgm.data[i]
+	flopGrid(pgm.data, pgm.height)
+}
+
+// Clone returns a deep copy of the PGM image.
+func (pgm *PGM) Clone() *PGM {
+	return &PGM{cloneGrid(pgm.data, pgm.width, pgm.height), pgm.width, pgm.height, pgm.magicNumber, pgm.max}
+}
+
+// Equal reports whether both PGM images have the same dimensions, max value and pixels.
+func (pgm *PGM) Equal(other *PGM) bool {
+	if pgm == nil || other == nil {
+		return pgm == other
 	}
+	if pgm.width != other.width || pgm.height != other.height || pgm.max != other.max {
+		return false
+	}
+	return equalGrid(pgm.data, other.data, pgm.width, pgm.height)
+}
+
+// SubImage returns a view of the part of the image inside r. The view shares
+// its pixels with the original image, so changes made through one are visible
+// in the other. r is clipped to the image bounds.
+func (pgm *PGM) SubImage(r Rectangle) *PGM {
+	r = r.Intersect(bounds(pgm.width, pgm.height))
+	return &PGM{subGrid(pgm.data, r), r.Dx(), r.Dy(), pgm.magicNumber, pgm.max}
+}
+
+// Crop keeps only the part of the PGM image inside r, like pnmcut.
+// r is clipped to the image bounds.
+func (pgm *PGM) Crop(r Rectangle) {
+	r = r.Intersect(bounds(pgm.width, pgm.height))
+	pgm.data = reframe(pgm.data, pgm.width, pgm.height, r.Dx(), r.Dy(), -r.Min.X, -r.Min.Y, 0)
+	pgm.width, pgm.height = r.Dx(), r.Dy()
+}
+
+// Pad adds borders of the given widths around the PGM image, filled with
+// fill, like pnmpad. A negative width removes pixels from that side instead;
+// when no column or no row is left, the image becomes 0×0.
+func (pgm *PGM) Pad(top, right, bottom, left int, fill uint8) {
+	width, height := canvasSize(pgm.width+left+right, pgm.height+top+bottom)
+	pgm.data = reframe(pgm.data, pgm.width, pgm.height, width, height, left, top, fill)
+	pgm.width, pgm.height = width, height
+}
+
+// CanvasResize changes the size of the PGM canvas to width×height without
+// scaling the image. The anchor decides which part of the image stays in
+// place; the image is cropped where the canvas shrinks and new pixels are set
+// to fill where it grows. A width or height of zero or less gives a 0×0
+// image.
+func (pgm *PGM) CanvasResize(width, height int, anchor Anchor, fill uint8) {
+	width, height = canvasSize(width, height)
+	dx, dy := anchor.offset(pgm.width, pgm.height, width, height)
+	pgm.data = reframe(pgm.data, pgm.width, pgm.height, width, height, dx, dy, fill)
+	pgm.width, pgm.height = width, height
 }
 
 // SetMagicNumber sets the magic number of the PGM image.
@@ -251,18 +298,10 @@ func (pgm *PGM) SetMagicNumber(magicNumber string) {
 	pgm.magicNumber = magicNumber
 }
 
+// SetMaxValue updates the max value of the PGM image and rescales the pixel
+// values to it, rounding to the nearest integer.
 func (pgm *PGM) SetMaxValue(maxValue uint8) {
-	for y := 0; y < pgm.height; y++ {
-		for x := 0; x < pgm.width; x++ {
-			// Scale the pixel values based on the new max value
-			scaledValue := float64(pgm.data[y][x]) * float64(maxValue) / float64(pgm.max)
-			// Round to the nearest integer
-			newValue := uint8(scaledValue)
-			pgm.data[y][x] = newValue
-		}
-	}
-
-	// Update the max value
+	pgm.mapSamples(scaleTable(pgm.max, maxValue))
 	pgm.max = maxValue
 }
 
@@ -283,20 +322,10 @@ func (pgm *PGM) Rotate90CW() {
 	pgm.width, pgm.height = pgm.height, pgm.width
 }
 
+// ToPBM converts the PGM image to a PBM image in which the pixels below half
+// the max value are black.
 func (pgm *PGM) ToPBM() *PBM {
-	pbm := &PBM{
-		data:        make([][]bool, pgm.height),
-		width:       pgm.width,
-		height:      pgm.height,
-		magicNumber: "P1",
-	}
-	for y := 0; y < pgm.height; y++ {
-		pbm.data[y] = make([]bool, pgm.width)
-		for x := 0; x < pgm.width; x++ {
-			pbm.data[y][x] = pgm.data[y][x] < uint8(pgm.max/2)
-		}
-	}
-	return pbm
+	return pgm.Threshold(pgm.max / 2)
 }
 
 func (pgm *PGM) PrintData() {
@@ -0,0 +1,200 @@
+package Netpbm
+
+import "math"
+
+// plane is a single channel of samples stored as floats. It is the working
+// format of the resampling and filtering code, which converts images to
+// planes, processes them, and converts the result back.
+type plane struct {
+	width, height int
+	pix           []float64
+}
+
+func newPlane(width, height int) *plane {
+	return &plane{width, height, make([]float64, width*height)}
+}
+
+func (p *plane) at(x, y int) float64 {
+	return p.pix[y*p.width+x]
+}
+
+func (p *plane) set(x, y int, value float64) {
+	p.pix[y*p.width+x] = value
+}
+
+// plane returns the samples of the PGM image normalized to [0, 1].
+// When linear is set the samples are decoded to linear light.
+func (pgm *PGM) plane(linear bool) *plane {
+	table := decodeTable(pgm.max, linear)
+	p := newPlane(pgm.width, pgm.height)
+	for y := 0; y < pgm.height; y++ {
+		for x := 0; x < pgm.width; x++ {
+			p.set(x, y, table[pgm.data[y][x]])
+		}
+	}
+	return p
+}
+
+// setPlane stores p back into the PGM image. Pixels are written in place when
+// the size is unchanged, so views created by SubImage are updated too.
+func (pgm *PGM) setPlane(p *plane, linear bool) {
+	if p.width != pgm.width || p.height != pgm.height {
+		pgm.data = newGrid[uint8](p.width, p.height)
+		pgm.width, pgm.height = p.width, p.height
+	}
+	for y := 0; y < pgm.height; y++ {
+		for x := 0; x < pgm.width; x++ {
+			pgm.data[y][x] = encodeSample(p.at(x, y), pgm.max, linear)
+		}
+	}
+}
+
+// planes returns the red, green and blue samples of the PPM image normalized
+// to [0, 1]. When linear is set the samples are decoded to linear light.
+func (ppm *PPM) planes(linear bool) [3]*plane {
+	table := decodeTable(ppm.max, linear)
+	var ps [3]*plane
+	for c := range ps {
+		ps[c] = newPlane(ppm.width, ppm.height)
+	}
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			pixel := ppm.data[y][x]
+			ps[0].set(x, y, table[pixel.R])
+			ps[1].set(x, y, table[pixel.G])
+			ps[2].set(x, y, table[pixel.B])
+		}
+	}
+	return ps
+}
+
+// setPlanes stores the red, green and blue planes back into the PPM image.
+// Pixels are written in place when the size is unchanged.
+func (ppm *PPM) setPlanes(ps [3]*plane, linear bool) {
+	if ps[0].width != ppm.width || ps[0].height != ppm.height {
+		ppm.data = newGrid[Pixel](ps[0].width, ps[0].height)
+		ppm.width, ppm.height = ps[0].width, ps[0].height
+	}
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			ppm.data[y][x] = Pixel{
+				R: encodeSample(ps[0].at(x, y), ppm.max, linear),
+				G: encodeSample(ps[1].at(x, y), ppm.max, linear),
+				B: encodeSample(ps[2].at(x, y), ppm.max, linear),
+			}
+		}
+	}
+}
+
+// bitPlane returns the PBM image as a plane holding 1 for black pixels and 0
+// for white ones.
+func (pbm *PBM) bitPlane() *plane {
+	p := newPlane(pbm.width, pbm.height)
+	for y := 0; y < pbm.height; y++ {
+		for x := 0; x < pbm.width; x++ {
+			if pbm.data[y][x] {
+				p.set(x, y, 1)
+			}
+		}
+	}
+	return p
+}
+
+// setBitPlane stores p back into the PBM image, turning every sample of at
+// least one half black.
+func (pbm *PBM) setBitPlane(p *plane) {
+	if p.width != pbm.width || p.height != pbm.height {
+		pbm.data = newGrid[bool](p.width, p.height)
+		pbm.width, pbm.height = p.width, p.height
+	}
+	for y := 0; y < pbm.height; y++ {
+		for x := 0; x < pbm.width; x++ {
+			pbm.data[y][x] = p.at(x, y) >= 0.5
+		}
+	}
+}
+
+// gaussianWeights returns a normalized 1D Gaussian kernel of standard
+// deviation sigma, truncated at three sigmas.
+func gaussianWeights(sigma float64) []float64 {
+	radius := int(math.Ceil(3 * sigma))
+	weights := make([]float64, 2*radius+1)
+	sum := 0.0
+	for i := range weights {
+		d := float64(i - radius)
+		weights[i] = math.Exp(-d * d / (2 * sigma * sigma))
+		sum += weights[i]
+	}
+	for i := range weights {
+		weights[i] /= sum
+	}
+	return weights
+}
+
+// separable returns p convolved with the odd-length row kernel horizontally
+// and the col kernel vertically, extending the borders according to mode.
+func (p *plane) separable(row, col []float64, mode EdgeMode) *plane {
+	tmp := newPlane(p.width, p.height)
+	r := len(row) / 2
+	for y := 0; y < p.height; y++ {
+		for x := 0; x < p.width; x++ {
+			sum := 0.0
+			for i, w := range row {
+				if sx := mode.index(x+i-r, p.width); sx >= 0 {
+					sum += w * p.at(sx, y)
+				}
+			}
+			tmp.set(x, y, sum)
+		}
+	}
+	out := newPlane(p.width, p.height)
+	r = len(col) / 2
+	for y := 0; y < p.height; y++ {
+		for x := 0; x < p.width; x++ {
+			sum := 0.0
+			for i, w := range col {
+				if sy := mode.index(y+i-r, p.height); sy >= 0 {
+					sum += w * tmp.at(x, sy)
+				}
+			}
+			out.set(x, y, sum)
+		}
+	}
+	return out
+}
+
+// summedArea holds summed-area tables of a plane and of its squares, which
+// give the mean and variance of any rectangle in constant time.
+type summedArea struct {
+	width, height int
+	sum, sq       []float64
+}
+
+func (p *plane) summedArea() *summedArea {
+	w := p.width + 1
+	s := &summedArea{p.width, p.height, make([]float64, w*(p.height+1)), make([]float64, w*(p.height+1))}
+	for y := 0; y < p.height; y++ {
+		rowSum, rowSq := 0.0, 0.0
+		for x := 0; x < p.width; x++ {
+			v := p.at(x, y)
+			rowSum += v
+			rowSq += v * v
+			s.sum[(y+1)*w+x+1] = s.sum[y*w+x+1] + rowSum
+			s.sq[(y+1)*w+x+1] = s.sq[y*w+x+1] + rowSq
+		}
+	}
+	return s
+}
+
+// stats returns the mean and standard deviation of the square window of the
+// given radius centered on (x, y), clipped to the plane.
+func (s *summedArea) stats(x, y, radius int) (float64, float64) {
+	x0, y0 := max(x-radius, 0), max(y-radius, 0)
+	x1, y1 := min(x+radius+1, s.width), min(y+radius+1, s.height)
+	w := s.width + 1
+	n := float64((x1 - x0) * (y1 - y0))
+	sum := s.sum[y1*w+x1] - s.sum[y0*w+x1] - s.sum[y1*w+x0] + s.sum[y0*w+x0]
+	sq := s.sq[y1*w+x1] - s.sq[y0*w+x1] - s.sq[y1*w+x0] + s.sq[y0*w+x0]
+	mean := sum / n
+	return mean, math.Sqrt(math.Max(sq/n-mean*mean, 0))
+}
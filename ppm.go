@@ -202,15 +202,10 @@ func (ppm *PPM) Save(filename string) error {
 	return nil
 }
 
+// Invert inverts the colors of the PPM image relative to its max value.
 func (ppm *PPM) Invert() {
-	for y := 0; y < ppm.height; y++ {
-		for x := 0; x < ppm.width; x++ {
-			pixel := &ppm.data[y][x]
-			pixel.R = 255 - pixel.R
-			pixel.G = 255 - pixel.G
-			pixel.B = 255 - pixel.B
-		}
-	}
+	table := invertTable(ppm.max)
+	ppm.mapChannels(table, table, table)
 }
 
 func (ppm *PPM) Flip() {
@@ -222,9 +217,60 @@ func (ppm *PPM) Flip() {
 }
 
 func (ppm *PPM) Flop() {
-	for y := 0; y < ppm.height/2; y++ {
-		ppm.data[y], ppm.data[ppm.height-y-1] = ppm.data[ppm.height-y-1], ppm.data[y]
+	flopGrid(ppm.data, ppm.height)
+}
+
+// Clone returns a deep copy of the PPM image.
+func (ppm *PPM) Clone() *PPM {
+	return &PPM{cloneGrid(ppm.data, ppm.width, ppm.height), ppm.width, ppm.height, ppm.magicNumber, ppm.max}
+}
+
+// Equal reports whether both PPM images have the same dimensions, max value and pixels.
+func (ppm *PPM) Equal(other *PPM) bool {
+	if ppm == nil || other == nil {
+		return ppm == other
+	}
+	if ppm.width != other.width || ppm.height != other.height || ppm.max != other.max {
+		return false
 	}
+	return equalGrid(ppm.data, other.data, ppm.width, ppm.height)
+}
+
+// SubImage returns a view of the part of the image inside r. The view shares
+// its pixels with the original image, so changes made through one are visible
+// in the other. r is clipped to the image bounds.
+func (ppm *PPM) SubImage(r Rectangle) *PPM {
+	r = r.Intersect(bounds(ppm.width, ppm.height))
+	return &PPM{subGrid(ppm.data, r), r.Dx(), r.Dy(), ppm.magicNumber, ppm.max}
+}
+
+// Crop keeps only the part of the PPM image inside r, like pnmcut.
+// r is clipped to the image bounds.
+func (ppm *PPM) Crop(r Rectangle) {
+	r = r.Intersect(bounds(ppm.width, ppm.height))
+	ppm.data = reframe(ppm.data, ppm.width, ppm.height, r.Dx(), r.Dy(), -r.Min.X, -r.Min.Y, Pixel{})
+	ppm.width, ppm.height = r.Dx(), r.Dy()
+}
+
+// Pad adds borders of the given widths around the PPM image, filled with
+// fill, like pnmpad. A negative width removes pixels from that side instead;
+// when no column or no row is left, the image becomes 0×0.
+func (ppm *PPM) Pad(top, right, bottom, left int, fill Pixel) {
+	width, height := canvasSize(ppm.width+left+right, ppm.height+top+bottom)
+	ppm.data = reframe(ppm.data, ppm.width, ppm.height, width, height, left, top, fill)
+	ppm.width, ppm.height = width, height
+}
+
+// CanvasResize changes the size of the PPM canvas to width×height without
+// scaling the image. The anchor decides which part of the image stays in
+// place; the image is cropped where the canvas shrinks and new pixels are set
+// to fill where it grows. A width or height of zero or less gives a 0×0
+// image.
+func (ppm *PPM) CanvasResize(width, height int, anchor Anchor, fill Pixel) {
+	width, height = canvasSize(width, height)
+	dx, dy := anchor.offset(ppm.width, ppm.height, width, height)
+	ppm.data = reframe(ppm.data, ppm.width, ppm.height, width, height, dx, dy, fill)
+	ppm.width, ppm.height = width, height
 }
 
 func (ppm *PPM) SetMagicNumber(magicNumber string) {
@@ -232,18 +278,11 @@ func (ppm *PPM) SetMagicNumber(magicNumber string) {
 }
 
 // SetMaxValue updates the maximum pixel value in the PPM structure
-// and scales the pixel values in data based on the new max value.
+// and scales the pixel values in data based on the new max value,
+// rounding to the nearest integer.
 func (ppm *PPM) SetMaxValue(maxValue uint8) {
-	for y := 0; y < ppm.height; y++ {
-		for x := 0; x < ppm.width; x++ {
-			// Scale the RGB values based on the new max value
-			ppm.data[y][x].R = uint8(float64(ppm.data[y][x].R) * float64(maxValue) / float64(ppm.max))
-			ppm.data[y][x].G = uint8(float64(ppm.data[y][x].G) * float64(maxValue) / float64(ppm.max))
-			ppm.data[y][x].B = uint8(float64(ppm.data[y][x].B) * float64(maxValue) / float64(ppm.max))
-		}
-	}
-
-	// Update the max value
+	table := scaleTable(ppm.max, maxValue)
+	ppm.mapChannels(table, table, table)
 	ppm.max = maxValue
 }
 
@@ -269,29 +308,10 @@ func (ppm *PPM) Rotate90CW() {
 	*ppm = newPPM
 }
 
-// ToPGM converts the PPM image to a PGM image (grayscale).
+// ToPGM converts the PPM image to a PGM image (grayscale) using the default
+// GrayLuminance conversion. Use ToPGMWith to select another conversion.
 func (ppm *PPM) ToPGM() *PGM {
-	pgm := &PGM{
-		width:       ppm.width,
-		height:      ppm.height,
-		magicNumber: "P2",
-		max:         ppm.max,
-	}
-
-	pgm.data = make([][]uint8, ppm.height)
-	for i := range pgm.data {
-		pgm.data[i] = make([]uint8, ppm.width)
-	}
-
-	for y := 0; y < ppm.height; y++ {
-		for x := 0; x < ppm.width; x++ {
-			// Convert RGB to grayscale
-			gray := uint8((int(ppm.data[y][x].R) + int(ppm.data[y][x].G) + int(ppm.data[y][x].B)) / 3)
-			pgm.data[y][x] = gray
-		}
-	}
-
-	return pgm
+	return ppm.ToPGMWith(GrayConversion{})
 }
 
 type Point struct {
@@ -302,33 +322,19 @@ type Point struct {
 func rgbToGray(color Pixel) uint8 {
 	// Use luminosity method for converting RGB to grayscale
 	// Gray = 0.299*R + 0.587*G + 0.114*B
-	return uint8(0.299*float64(color.R) + 0.587*float64(color.G) + 0.114*float64(color.B))
+	return uint8(0.299*float64(color.R) + 0.587*float64(color.G) + 0.114*float64(color.B) + 0.5)
 }
 
-func (ppm *PPM) ToPBM() *PBM {
-	pbm := &PBM{
-		width:       ppm.width,
-		height:      ppm.height,
-		magicNumber: "P1",
-	}
-
-	pbm.data = make([][]bool, ppm.height)
-	for i := range pbm.data {
-		pbm.data[i] = make([]bool, ppm.width)
-	}
-
-	// Set a threshold for binary conversion
-	threshold := uint8(ppm.max / 2)
+// luma returns a PGM image holding the BT.601 luma of every pixel of the PPM
+// image.
+func (ppm *PPM) luma() *PGM {
+	return ppm.ToPGMWith(GrayConversion{Mode: GrayBT601})
+}
 
-	for y := 0; y < ppm.height; y++ {
-		for x := 0; x < ppm.width; x++ {
-			// Calculate the average intensity of RGB values
-			average := (uint16(ppm.data[y][x].R) + uint16(ppm.data[y][x].G) + uint16(ppm.data[y][x].B)) / 3
-			// Set the binary value based on the threshold
-			pbm.data[y][x] = average < uint16(threshold)
-		}
-	}
-	return pbm
+// ToPBM converts the PPM image to a PBM image in which the pixels whose luma
+// is below half the max value are black, the same rule as PGM.ToPBM.
+func (ppm *PPM) ToPBM() *PBM {
+	return ppm.luma().ToPBM()
 }
 
 // pbm.Save("tetconvert.pgm")
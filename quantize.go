@@ -0,0 +1,378 @@
+package Netpbm
+
+import (
+	"errors"
+	"sort"
+)
+
+// ColorCount is a color of an image along with the number of pixels using it.
+type ColorCount struct {
+	Color Pixel
+	Count int
+}
+
+// QuantizeMethod selects how Quantize chooses its palette.
+type QuantizeMethod int
+
+const (
+	// MedianCut repeatedly splits the box of colors with the widest range
+	// at its median, like pnmcolormap.
+	MedianCut QuantizeMethod = iota
+	// Octree builds a tree of colors on their bits and merges the least
+	// used leaves until few enough remain.
+	Octree
+	// KMeans refines the median cut palette with k-means clustering, which
+	// is slower but lowers the overall color error.
+	KMeans
+)
+
+// Colors returns the distinct colors of the PPM image with the number of
+// pixels using each of them, most used first, like ppmhist.
+func (ppm *PPM) Colors() []ColorCount {
+	counts := make(map[Pixel]int)
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			counts[ppm.data[y][x]]++
+		}
+	}
+	colors := make([]ColorCount, 0, len(counts))
+	for c, n := range counts {
+		colors = append(colors, ColorCount{c, n})
+	}
+	sort.Slice(colors, func(i, j int) bool {
+		a, b := colors[i], colors[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.Color.R != b.Color.R {
+			return a.Color.R < b.Color.R
+		}
+		if a.Color.G != b.Color.G {
+			return a.Color.G < b.Color.G
+		}
+		return a.Color.B < b.Color.B
+	})
+	return colors
+}
+
+// component returns channel c of p as an int.
+func component(p Pixel, c int) int {
+	switch c {
+	case 1:
+		return int(p.G)
+	case 2:
+		return int(p.B)
+	}
+	return int(p.R)
+}
+
+// meanColor returns the average of colors weighted by their counts.
+func meanColor(colors []ColorCount) Pixel {
+	var r, g, b, n int
+	for _, c := range colors {
+		r += int(c.Color.R) * c.Count
+		g += int(c.Color.G) * c.Count
+		b += int(c.Color.B) * c.Count
+		n += c.Count
+	}
+	if n == 0 {
+		return Pixel{}
+	}
+	return Pixel{uint8((r + n/2) / n), uint8((g + n/2) / n), uint8((b + n/2) / n)}
+}
+
+// medianCut returns a palette of at most n colors for the given colors.
+func medianCut(colors []ColorCount, n int) []Pixel {
+	boxes := [][]ColorCount{colors}
+	for len(boxes) < n {
+		// Split the box with the widest channel range, weighted by the
+		// number of pixels it holds.
+		best, bestChannel, bestScore := -1, 0, 0
+		for i, box := range boxes {
+			if len(box) < 2 {
+				continue
+			}
+			pixels := 0
+			for _, c := range box {
+				pixels += c.Count
+			}
+			for ch := 0; ch < 3; ch++ {
+				lo, hi := 255, 0
+				for _, c := range box {
+					v := component(c.Color, ch)
+					lo, hi = min(lo, v), max(hi, v)
+				}
+				if score := (hi - lo) * pixels; hi > lo && score >= bestScore {
+					best, bestChannel, bestScore = i, ch, score
+				}
+			}
+		}
+		if best < 0 {
+			break
+		}
+
+		box := boxes[best]
+		sort.Slice(box, func(i, j int) bool {
+			return component(box[i].Color, bestChannel) < component(box[j].Color, bestChannel)
+		})
+		total := 0
+		for _, c := range box {
+			total += c.Count
+		}
+		split, seen := 1, 0
+		for i, c := range box[:len(box)-1] {
+			seen += c.Count
+			if seen*2 >= total {
+				split = i + 1
+				break
+			}
+		}
+		boxes[best] = box[:split]
+		boxes = append(boxes, box[split:])
+	}
+
+	palette := make([]Pixel, len(boxes))
+	for i, box := range boxes {
+		palette[i] = meanColor(box)
+	}
+	return palette
+}
+
+// octreeNode is a node of the color octree. Leaves accumulate the sums of
+// the colors that reached them.
+type octreeNode struct {
+	children   [8]*octreeNode
+	r, g, b, n int
+	leaf       bool
+}
+
+// octree returns a palette of at most n colors for the given colors.
+func octree(colors []ColorCount, n int) []Pixel {
+	root := &octreeNode{}
+	var levels [8][]*octreeNode
+	leaves := 0
+	for _, c := range colors {
+		node := root
+		for level := 0; level < 8 && !node.leaf; level++ {
+			shift := 7 - level
+			i := int(c.Color.R>>shift&1)<<2 | int(c.Color.G>>shift&1)<<1 | int(c.Color.B>>shift&1)
+			if node.children[i] == nil {
+				child := &octreeNode{leaf: level == 7}
+				node.children[i] = child
+				if child.leaf {
+					leaves++
+				} else {
+					levels[level+1] = append(levels[level+1], child)
+				}
+			}
+			node = node.children[i]
+		}
+		node.r += int(c.Color.R) * c.Count
+		node.g += int(c.Color.G) * c.Count
+		node.b += int(c.Color.B) * c.Count
+		node.n += c.Count
+	}
+	levels[0] = []*octreeNode{root}
+
+	// total returns the number of pixels below node.
+	var total func(node *octreeNode) int
+	total = func(node *octreeNode) int {
+		if node.leaf {
+			return node.n
+		}
+		sum := 0
+		for _, child := range node.children {
+			if child != nil {
+				sum += total(child)
+			}
+		}
+		return sum
+	}
+
+	// Merge the least used deepest nodes into leaves until few enough
+	// leaves remain. The children of the nodes of a level are all leaves by
+	// then. When merging a whole node would leave fewer than n leaves, only
+	// its least used children are merged together, so exactly n remain.
+	for level := 7; level >= 0 && leaves > n; level-- {
+		nodes := levels[level]
+		sort.Slice(nodes, func(i, j int) bool { return total(nodes[i]) < total(nodes[j]) })
+		for _, node := range nodes {
+			if leaves <= n {
+				break
+			}
+			var children []*octreeNode
+			for i, child := range node.children {
+				if child != nil {
+					children = append(children, child)
+					node.children[i] = nil
+				}
+			}
+			into := node
+			if excess := leaves - n; len(children) > excess+1 {
+				sort.Slice(children, func(i, j int) bool { return children[i].n < children[j].n })
+				for i, child := range children[excess+1:] {
+					node.children[i] = child
+				}
+				into = &octreeNode{leaf: true}
+				node.children[len(children)-excess-1] = into
+				children = children[:excess+1]
+			}
+			for _, child := range children {
+				into.r += child.r
+				into.g += child.g
+				into.b += child.b
+				into.n += child.n
+			}
+			into.leaf = true
+			leaves -= len(children) - 1
+		}
+	}
+
+	var palette []Pixel
+	var collect func(node *octreeNode)
+	collect = func(node *octreeNode) {
+		if node.leaf {
+			if node.n > 0 {
+				palette = append(palette, Pixel{
+					uint8((node.r + node.n/2) / node.n),
+					uint8((node.g + node.n/2) / node.n),
+					uint8((node.b + node.n/2) / node.n),
+				})
+			}
+			return
+		}
+		for _, child := range node.children {
+			if child != nil {
+				collect(child)
+			}
+		}
+	}
+	collect(root)
+	return palette
+}
+
+// colorDistance returns the squared Euclidean distance between two colors.
+func colorDistance(a, b Pixel) int {
+	dr := int(a.R) - int(b.R)
+	dg := int(a.G) - int(b.G)
+	db := int(a.B) - int(b.B)
+	return dr*dr + dg*dg + db*db
+}
+
+// nearestColor returns the index of the palette color closest to p.
+func nearestColor(palette []Pixel, p Pixel) int {
+	best, bestDistance := 0, -1
+	for i, c := range palette {
+		if d := colorDistance(p, c); bestDistance < 0 || d < bestDistance {
+			best, bestDistance = i, d
+		}
+	}
+	return best
+}
+
+// kMeans refines the median cut palette of at most n colors with Lloyd's
+// algorithm.
+func kMeans(colors []ColorCount, n int) []Pixel {
+	palette := medianCut(colors, n)
+	assignment := make([]int, len(colors))
+	for i := range assignment {
+		assignment[i] = -1
+	}
+	for iteration := 0; iteration < 16; iteration++ {
+		changed := false
+		clusters := make([][]ColorCount, len(palette))
+		for i, c := range colors {
+			k := nearestColor(palette, c.Color)
+			if k != assignment[i] {
+				assignment[i] = k
+				changed = true
+			}
+			clusters[k] = append(clusters[k], c)
+		}
+		if !changed {
+			break
+		}
+		for k, cluster := range clusters {
+			if len(cluster) > 0 {
+				palette[k] = meanColor(cluster)
+			}
+		}
+	}
+	return palette
+}
+
+// Quantize reduces the PPM image to at most n colors chosen with the given
+// method and returns the palette. When dither is set, Floyd–Steinberg error
+// diffusion is used while mapping pixels to the palette.
+func (ppm *PPM) Quantize(n int, method QuantizeMethod, dither bool) []Pixel {
+	colors := ppm.Colors()
+	if n <= 0 || len(colors) == 0 {
+		return nil
+	}
+	if len(colors) <= n {
+		palette := make([]Pixel, len(colors))
+		for i, c := range colors {
+			palette[i] = c.Color
+		}
+		return palette
+	}
+
+	var palette []Pixel
+	switch method {
+	case Octree:
+		palette = octree(colors, n)
+	case KMeans:
+		palette = kMeans(colors, n)
+	default:
+		palette = medianCut(colors, n)
+	}
+	ppm.remap(palette, dither)
+	return palette
+}
+
+// Remap replaces every pixel of the PPM image with the closest color of the
+// palette image, like pnmremap. The palette colors are rescaled to the max
+// value of the image. When dither is set, Floyd–Steinberg error diffusion is
+// used.
+func (ppm *PPM) Remap(palette *PPM, dither bool) error {
+	if palette == nil || palette.width == 0 || palette.height == 0 {
+		return errors.New("palette image is empty")
+	}
+	colors := palette.Colors()
+	table := scaleTable(palette.max, ppm.max)
+	p := make([]Pixel, len(colors))
+	for i, c := range colors {
+		p[i] = Pixel{table[c.Color.R], table[c.Color.G], table[c.Color.B]}
+	}
+	ppm.remap(p, dither)
+	return nil
+}
+
+// remap replaces every pixel with the closest palette color.
+func (ppm *PPM) remap(palette []Pixel, dither bool) {
+	if !dither {
+		cache := make(map[Pixel]Pixel)
+		ppm.mapPixels(func(p Pixel) Pixel {
+			c, ok := cache[p]
+			if !ok {
+				c = palette[nearestColor(palette, p)]
+				cache[p] = c
+			}
+			return c
+		})
+		return
+	}
+
+	ps := ppm.planes(false)
+	m := float64(ppm.max)
+	out := make([]float64, 3)
+	diffusionKernels[FloydSteinberg].diffuse(ps[:], true, func(x, y int, v []float64) []float64 {
+		target := pixelOf(v[0], v[1], v[2], ppm.max)
+		c := palette[nearestColor(palette, target)]
+		ppm.data[y][x] = c
+		if m > 0 {
+			out[0], out[1], out[2] = float64(c.R)/m, float64(c.G)/m, float64(c.B)/m
+		}
+		return out
+	})
+}
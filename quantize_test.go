@@ -0,0 +1,23 @@
+package Netpbm
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestQuantizePaletteSize(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	images := []*PPM{randomPPM(r, 40, 30, 255), randomPPM(r, 16, 16, 7), randomPPM(r, 30, 20, 1)}
+	for i, img := range images {
+		distinct := len(img.Colors())
+		for _, method := range []QuantizeMethod{MedianCut, Octree} {
+			for _, n := range []int{1, 2, 3, 5, 16, 64, 255, 256} {
+				palette := img.Clone().Quantize(n, method, false)
+				if want := min(n, distinct); len(palette) != want {
+					t.Errorf("image %d with %d colors, method %d, n %d: palette has %d colors, want %d",
+						i, distinct, method, n, len(palette), want)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package Netpbm
+
+import "math"
+
+// WindowShape selects the neighborhood of the rank filters.
+type WindowShape int
+
+const (
+	// WindowSquare uses the square of side 2×radius+1 centered on the pixel.
+	WindowSquare WindowShape = iota
+	// WindowCircle uses the pixels whose distance to the center is at most
+	// the radius.
+	WindowCircle
+)
+
+// spans returns, for every row offset from -radius to radius, the half width
+// of the window on that row.
+func (shape WindowShape) spans(radius int) []int {
+	spans := make([]int, 2*radius+1)
+	for dy := -radius; dy <= radius; dy++ {
+		if shape == WindowCircle {
+			spans[dy+radius] = int(math.Sqrt(float64(radius*radius - dy*dy)))
+		} else {
+			spans[dy+radius] = radius
+		}
+	}
+	return spans
+}
+
+// rankIndex returns the zero-based rank of the given percentile among n
+// sorted values.
+func rankIndex(percentile float64, n int) int {
+	p := math.Max(0, math.Min(percentile, 100))
+	return int(math.Round(p / 100 * float64(n-1)))
+}
+
+// selectRank returns the value of the given rank in a histogram.
+func selectRank(hist []int, rank int) uint8 {
+	seen := 0
+	for v, n := range hist {
+		seen += n
+		if seen > rank {
+			return uint8(v)
+		}
+	}
+	return uint8(len(hist) - 1)
+}
+
+// rankFilter returns the grid where every sample is replaced with the given
+// percentile of its window, repeating edge samples past the borders.
+// Samples above maxValue are treated as maxValue. Square windows use
+// Perreault's method: a histogram per column is slid down the image and the
+// window histogram is updated by whole columns, so the cost per pixel does
+// not depend on the radius. Circular windows use Huang's method, adding and
+// removing one sample per window row, so their cost per pixel grows linearly
+// with the radius.
+func rankFilter(data [][]uint8, width, height int, maxValue uint8, radius int, shape WindowShape, percentile float64) [][]uint8 {
+	out := newGrid[uint8](width, height)
+	bins := int(maxValue) + 1
+	spans := shape.spans(radius)
+	n := 0
+	for _, s := range spans {
+		n += 2*s + 1
+	}
+	rank := rankIndex(percentile, n)
+	sample := func(x, y int) int {
+		return int(min(data[clamp(y, 0, height-1)][clamp(x, 0, width-1)], maxValue))
+	}
+
+	hist := make([]int, bins)
+	if shape == WindowSquare {
+		columns := make([][]int, width)
+		for x := range columns {
+			columns[x] = make([]int, bins)
+			for dy := -radius; dy <= radius; dy++ {
+				columns[x][sample(x, dy)]++
+			}
+		}
+		column := func(x int) []int { return columns[clamp(x, 0, width-1)] }
+		for y := 0; y < height; y++ {
+			if y > 0 {
+				for x := range columns {
+					columns[x][sample(x, y-radius-1)]--
+					columns[x][sample(x, y+radius)]++
+				}
+			}
+			clear(hist)
+			for dx := -radius; dx <= radius; dx++ {
+				for v, c := range column(dx) {
+					hist[v] += c
+				}
+			}
+			for x := 0; x < width; x++ {
+				out[y][x] = selectRank(hist, rank)
+				added, removed := column(x+radius+1), column(x-radius)
+				for v := range hist {
+					hist[v] += added[v] - removed[v]
+				}
+			}
+		}
+		return out
+	}
+
+	for y := 0; y < height; y++ {
+		clear(hist)
+		for dy := -radius; dy <= radius; dy++ {
+			s := spans[dy+radius]
+			for dx := -s; dx <= s; dx++ {
+				hist[sample(dx, y+dy)]++
+			}
+		}
+		for x := 0; x < width; x++ {
+			out[y][x] = selectRank(hist, rank)
+			for dy := -radius; dy <= radius; dy++ {
+				s := spans[dy+radius]
+				hist[sample(x-s, y+dy)]--
+				hist[sample(x+s+1, y+dy)]++
+			}
+		}
+	}
+	return out
+}
+
+// rank replaces every sample of the PGM image with the given percentile of
+// its window.
+func (pgm *PGM) rank(radius int, shape WindowShape, percentile float64) {
+	if radius <= 0 {
+		return
+	}
+	out := rankFilter(pgm.data, pgm.width, pgm.height, pgm.max, radius, shape, percentile)
+	for y := range out {
+		copy(pgm.data[y], out[y])
+	}
+}
+
+// Median replaces every sample of the PGM image with the median of its
+// window of the given radius, which removes salt-and-pepper noise while
+// keeping edges. With square windows large radii are as fast as small
+// ones; circular windows cost time proportional to the radius.
+func (pgm *PGM) Median(radius int, shape WindowShape) {
+	pgm.rank(radius, shape, 50)
+}
+
+// Min replaces every sample of the PGM image with the darkest sample of its
+// window of the given radius.
+func (pgm *PGM) Min(radius int, shape WindowShape) {
+	pgm.rank(radius, shape, 0)
+}
+
+// Max replaces every sample of the PGM image with the brightest sample of
+// its window of the given radius.
+func (pgm *PGM) Max(radius int, shape WindowShape) {
+	pgm.rank(radius, shape, 100)
+}
+
+// Percentile replaces every sample of the PGM image with the given
+// percentile, from 0 to 100, of its window of the given radius.
+func (pgm *PGM) Percentile(radius int, shape WindowShape, percentile float64) {
+	pgm.rank(radius, shape, percentile)
+}
+
+// rank applies the percentile filter to every channel of the PPM image.
+func (ppm *PPM) rank(radius int, shape WindowShape, percentile float64) {
+	if radius <= 0 {
+		return
+	}
+	for _, c := range []Channel{Red, Green, Blue} {
+		channel := ppm.ExtractChannel(c)
+		out := rankFilter(channel.data, ppm.width, ppm.height, ppm.max, radius, shape, percentile)
+		for y := 0; y < ppm.height; y++ {
+			for x := 0; x < ppm.width; x++ {
+				c.set(&ppm.data[y][x], out[y][x])
+			}
+		}
+	}
+}
+
+// Median applies a median filter to every channel of the PPM image
+// separately. The result may contain colors absent from the window; use
+// VectorMedian to avoid that.
+func (ppm *PPM) Median(radius int, shape WindowShape) {
+	ppm.rank(radius, shape, 50)
+}
+
+// Min applies a minimum filter to every channel of the PPM image.
+func (ppm *PPM) Min(radius int, shape WindowShape) {
+	ppm.rank(radius, shape, 0)
+}
+
+// Max applies a maximum filter to every channel of the PPM image.
+func (ppm *PPM) Max(radius int, shape WindowShape) {
+	ppm.rank(radius, shape, 100)
+}
+
+// Percentile applies a percentile filter to every channel of the PPM image.
+func (ppm *PPM) Percentile(radius int, shape WindowShape, percentile float64) {
+	ppm.rank(radius, shape, percentile)
+}
+
+// VectorMedian replaces every pixel of the PPM image with the pixel of its
+// window whose summed distance to the others, measured channel by channel,
+// is smallest. Unlike Median it never creates new colors. The distances are
+// taken from per-channel histograms, so the cost grows with the window area.
+// Samples above the max value are treated as the max value.
+func (ppm *PPM) VectorMedian(radius int, shape WindowShape) {
+	if radius <= 0 {
+		return
+	}
+	spans := shape.spans(radius)
+	bins := int(ppm.max) + 1
+	var hist, count, sum [3][]int
+	for c := range hist {
+		hist[c] = make([]int, bins)
+		count[c] = make([]int, bins+1)
+		sum[c] = make([]int, bins+1)
+	}
+	var window []Pixel
+	out := newGrid[Pixel](ppm.width, ppm.height)
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			window = window[:0]
+			for c := range hist {
+				clear(hist[c])
+			}
+			for dy := -radius; dy <= radius; dy++ {
+				sy := clamp(y+dy, 0, ppm.height-1)
+				s := spans[dy+radius]
+				for dx := -s; dx <= s; dx++ {
+					p := ppm.data[sy][clamp(x+dx, 0, ppm.width-1)]
+					p = Pixel{min(p.R, ppm.max), min(p.G, ppm.max), min(p.B, ppm.max)}
+					window = append(window, p)
+					hist[0][p.R]++
+					hist[1][p.G]++
+					hist[2][p.B]++
+				}
+			}
+			// Prefix counts and sums give the summed distance from any
+			// level to the window in constant time.
+			for c := range hist {
+				for v, n := range hist[c] {
+					count[c][v+1] = count[c][v] + n
+					sum[c][v+1] = sum[c][v] + n*v
+				}
+			}
+			n := len(window)
+			distance := func(c, v int) int {
+				below, total := count[c][v], sum[c][bins]
+				return v*below - sum[c][v] + (total - sum[c][v]) - v*(n-below)
+			}
+			best, bestDistance := window[0], -1
+			for _, p := range window {
+				d := distance(0, int(p.R)) + distance(1, int(p.G)) + distance(2, int(p.B))
+				if bestDistance < 0 || d < bestDistance {
+					best, bestDistance = p, d
+				}
+			}
+			out[y][x] = best
+		}
+	}
+	for y := range out {
+		copy(ppm.data[y], out[y])
+	}
+}
@@ -0,0 +1,70 @@
+package Netpbm
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestRankFilterMatchesSort(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	pgm := randomPGM(r, 23, 17, 200)
+	for _, shape := range []WindowShape{WindowSquare, WindowCircle} {
+		for radius := 1; radius <= 4; radius++ {
+			spans := shape.spans(radius)
+			for _, percentile := range []float64{0, 30, 50, 100} {
+				out := rankFilter(pgm.data, pgm.width, pgm.height, pgm.max, radius, shape, percentile)
+				for y := 0; y < pgm.height; y++ {
+					for x := 0; x < pgm.width; x++ {
+						var window []int
+						for dy := -radius; dy <= radius; dy++ {
+							s := spans[dy+radius]
+							for dx := -s; dx <= s; dx++ {
+								window = append(window, int(pgm.data[clamp(y+dy, 0, pgm.height-1)][clamp(x+dx, 0, pgm.width-1)]))
+							}
+						}
+						sort.Ints(window)
+						if want := window[rankIndex(percentile, len(window))]; int(out[y][x]) != want {
+							t.Fatalf("shape %d radius %d percentile %g: (%d, %d) is %d, want %d",
+								shape, radius, percentile, x, y, out[y][x], want)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestRankFiltersSamplesAboveMax(t *testing.T) {
+	// Readers accept samples above the max value; the filters treat them as
+	// the max value instead of indexing past their histograms.
+	pgm := flatPGM(5, 5, 200, 100)
+	pgm.Median(1, WindowSquare)
+	pgm.Max(2, WindowCircle)
+	for y := range pgm.data {
+		for x, v := range pgm.data[y] {
+			if v != 100 {
+				t.Fatalf("PGM sample (%d, %d) is %d, want 100", x, y, v)
+			}
+		}
+	}
+
+	ppm := &PPM{newGrid[Pixel](5, 5), 5, 5, "P3", 100}
+	for y := range ppm.data {
+		for x := range ppm.data[y] {
+			ppm.data[y][x] = Pixel{200, 50, 255}
+		}
+	}
+	median := ppm.Clone()
+	median.Median(1, WindowSquare)
+	ppm.VectorMedian(1, WindowSquare)
+	for _, img := range []*PPM{median, ppm} {
+		for y := range img.data {
+			for x, p := range img.data[y] {
+				if p != (Pixel{100, 50, 100}) {
+					t.Fatalf("PPM pixel (%d, %d) is %v, want {100 50 100}", x, y, p)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,289 @@
+package Netpbm
+
+import "math"
+
+// Filter selects the interpolation kernel used when resampling an image.
+type Filter int
+
+const (
+	// NearestNeighbor copies the closest source pixel.
+	NearestNeighbor Filter = iota
+	// Bilinear interpolates linearly between the two closest pixels on each axis.
+	Bilinear
+	// Bicubic uses the Catmull-Rom cubic spline over four pixels on each axis.
+	Bicubic
+	// Lanczos3 uses a three-lobed windowed sinc over six pixels on each axis.
+	Lanczos3
+)
+
+// support returns the radius of the filter kernel in source pixels.
+func (f Filter) support() float64 {
+	switch f {
+	case Bilinear:
+		return 1
+	case Bicubic:
+		return 2
+	case Lanczos3:
+		return 3
+	}
+	return 0.5
+}
+
+// kernel evaluates the filter kernel at distance x from its center.
+func (f Filter) kernel(x float64) float64 {
+	x = math.Abs(x)
+	switch f {
+	case Bilinear:
+		if x < 1 {
+			return 1 - x
+		}
+	case Bicubic:
+		if x < 1 {
+			return (1.5*x-2.5)*x*x + 1
+		}
+		if x < 2 {
+			return ((-0.5*x+2.5)*x-4)*x + 2
+		}
+	case Lanczos3:
+		if x == 0 {
+			return 1
+		}
+		if x < 3 {
+			return 3 * math.Sin(math.Pi*x) * math.Sin(math.Pi*x/3) / (math.Pi * math.Pi * x * x)
+		}
+	default:
+		if x <= 0.5 {
+			return 1
+		}
+	}
+	return 0
+}
+
+// contribution lists the source samples, and their weights, that make up
+// one destination sample along an axis.
+type contribution struct {
+	index  []int
+	weight []float64
+}
+
+// contributions computes the weights used to resample srcLen samples to
+// dstLen samples with the given filter. When shrinking, the kernel is
+// stretched so that every source sample contributes.
+func contributions(srcLen, dstLen int, filter Filter) []contribution {
+	scale := float64(dstLen) / float64(srcLen)
+	contribs := make([]contribution, dstLen)
+	if filter == NearestNeighbor {
+		for i := range contribs {
+			src := clamp(int((float64(i)+0.5)/scale), 0, srcLen-1)
+			contribs[i] = contribution{[]int{src}, []float64{1}}
+		}
+		return contribs
+	}
+
+	filterScale := 1.0
+	if scale < 1 {
+		filterScale = 1 / scale
+	}
+	support := filter.support() * filterScale
+	for i := range contribs {
+		center := (float64(i) + 0.5) / scale
+		left := int(math.Floor(center - support))
+		right := int(math.Ceil(center + support))
+		var c contribution
+		sum := 0.0
+		for j := left; j <= right; j++ {
+			w := filter.kernel((float64(j) + 0.5 - center) / filterScale)
+			if w == 0 {
+				continue
+			}
+			c.index = append(c.index, clamp(j, 0, srcLen-1))
+			c.weight = append(c.weight, w)
+			sum += w
+		}
+		for k := range c.weight {
+			c.weight[k] /= sum
+		}
+		contribs[i] = c
+	}
+	return contribs
+}
+
+// areaContributions computes box weights proportional to how much of each
+// destination sample's footprint every source sample covers.
+func areaContributions(srcLen, dstLen int) []contribution {
+	scale := float64(srcLen) / float64(dstLen)
+	contribs := make([]contribution, dstLen)
+	for i := range contribs {
+		start, end := float64(i)*scale, float64(i+1)*scale
+		var c contribution
+		for j := int(start); j < srcLen && float64(j) < end; j++ {
+			w := math.Min(end, float64(j+1)) - math.Max(start, float64(j))
+			if w <= 0 {
+				continue
+			}
+			c.index = append(c.index, j)
+			c.weight = append(c.weight, w/(end-start))
+		}
+		contribs[i] = c
+	}
+	return contribs
+}
+
+// resample returns a new plane computed with separate horizontal and vertical
+// passes using the given per-axis contributions.
+func (p *plane) resample(xContribs, yContribs []contribution) *plane {
+	width, height := len(xContribs), len(yContribs)
+	tmp := newPlane(width, p.height)
+	for y := 0; y < p.height; y++ {
+		row := p.pix[y*p.width : (y+1)*p.width]
+		for x, c := range xContribs {
+			sum := 0.0
+			for k, i := range c.index {
+				sum += row[i] * c.weight[k]
+			}
+			tmp.set(x, y, sum)
+		}
+	}
+	out := newPlane(width, height)
+	for y, c := range yContribs {
+		for x := 0; x < width; x++ {
+			sum := 0.0
+			for k, i := range c.index {
+				sum += tmp.at(x, i) * c.weight[k]
+			}
+			out.set(x, y, sum)
+		}
+	}
+	return out
+}
+
+// resize returns p scaled to width×height with the given filter.
+func (p *plane) resize(width, height int, filter Filter) *plane {
+	return p.resample(contributions(p.width, width, filter), contributions(p.height, height, filter))
+}
+
+// fitSize returns the largest size with the aspect ratio of width×height
+// that fits inside maxWidth×maxHeight.
+func fitSize(width, height, maxWidth, maxHeight int) (int, int) {
+	scale := math.Min(float64(maxWidth)/float64(width), float64(maxHeight)/float64(height))
+	return scaledSize(width, height, scale)
+}
+
+// fillSize returns the smallest size with the aspect ratio of width×height
+// that covers minWidth×minHeight.
+func fillSize(width, height, minWidth, minHeight int) (int, int) {
+	scale := math.Max(float64(minWidth)/float64(width), float64(minHeight)/float64(height))
+	return scaledSize(width, height, scale)
+}
+
+func scaledSize(width, height int, scale float64) (int, int) {
+	w := int(math.Round(float64(width) * scale))
+	h := int(math.Round(float64(height) * scale))
+	return max(w, 1), max(h, 1)
+}
+
+// Resize scales the PGM image to width×height with the given filter, like
+// pamscale. Interpolation is done in linear light so that averaged tones
+// keep their brightness. Nothing happens if a dimension is not positive.
+func (pgm *PGM) Resize(width, height int, filter Filter) {
+	if width <= 0 || height <= 0 || pgm.width <= 0 || pgm.height <= 0 {
+		return
+	}
+	pgm.setPlane(pgm.plane(true).resize(width, height, filter), true)
+}
+
+// Fit scales the PGM image to the largest size that fits inside
+// maxWidth×maxHeight while preserving its aspect ratio.
+func (pgm *PGM) Fit(maxWidth, maxHeight int, filter Filter) {
+	if maxWidth <= 0 || maxHeight <= 0 || pgm.width <= 0 || pgm.height <= 0 {
+		return
+	}
+	w, h := fitSize(pgm.width, pgm.height, maxWidth, maxHeight)
+	pgm.Resize(w, h, filter)
+}
+
+// Fill scales the PGM image, preserving its aspect ratio, until it covers
+// width×height, then crops the overflow evenly from both sides.
+func (pgm *PGM) Fill(width, height int, filter Filter) {
+	if width <= 0 || height <= 0 || pgm.width <= 0 || pgm.height <= 0 {
+		return
+	}
+	w, h := fillSize(pgm.width, pgm.height, width, height)
+	pgm.Resize(w, h, filter)
+	pgm.CanvasResize(width, height, AnchorCenter, 0)
+}
+
+// Resize scales the PPM image to width×height with the given filter, like
+// pamscale. Interpolation is done in linear light so that averaged colors
+// keep their brightness. Nothing happens if a dimension is not positive.
+func (ppm *PPM) Resize(width, height int, filter Filter) {
+	if width <= 0 || height <= 0 || ppm.width <= 0 || ppm.height <= 0 {
+		return
+	}
+	ps := ppm.planes(true)
+	xContribs := contributions(ppm.width, width, filter)
+	yContribs := contributions(ppm.height, height, filter)
+	for c := range ps {
+		ps[c] = ps[c].resample(xContribs, yContribs)
+	}
+	ppm.setPlanes(ps, true)
+}
+
+// Fit scales the PPM image to the largest size that fits inside
+// maxWidth×maxHeight while preserving its aspect ratio.
+func (ppm *PPM) Fit(maxWidth, maxHeight int, filter Filter) {
+	if maxWidth <= 0 || maxHeight <= 0 || ppm.width <= 0 || ppm.height <= 0 {
+		return
+	}
+	w, h := fitSize(ppm.width, ppm.height, maxWidth, maxHeight)
+	ppm.Resize(w, h, filter)
+}
+
+// Fill scales the PPM image, preserving its aspect ratio, until it covers
+// width×height, then crops the overflow evenly from both sides.
+func (ppm *PPM) Fill(width, height int, filter Filter) {
+	if width <= 0 || height <= 0 || ppm.width <= 0 || ppm.height <= 0 {
+		return
+	}
+	w, h := fillSize(ppm.width, ppm.height, width, height)
+	ppm.Resize(w, h, filter)
+	ppm.CanvasResize(width, height, AnchorCenter, Pixel{})
+}
+
+// Resize scales the PBM image to width×height. NearestNeighbor copies the
+// closest source pixel; every other filter uses area coverage, turning a
+// pixel black when at least half of its footprint in the source is black.
+// Nothing happens if a dimension is not positive.
+func (pbm *PBM) Resize(width, height int, filter Filter) {
+	if width <= 0 || height <= 0 || pbm.width <= 0 || pbm.height <= 0 {
+		return
+	}
+	xContribs := contributions(pbm.width, width, NearestNeighbor)
+	yContribs := contributions(pbm.height, height, NearestNeighbor)
+	if filter != NearestNeighbor {
+		xContribs = areaContributions(pbm.width, width)
+		yContribs = areaContributions(pbm.height, height)
+	}
+	pbm.setBitPlane(pbm.bitPlane().resample(xContribs, yContribs))
+}
+
+// Fit scales the PBM image to the largest size that fits inside
+// maxWidth×maxHeight while preserving its aspect ratio.
+func (pbm *PBM) Fit(maxWidth, maxHeight int, filter Filter) {
+	if maxWidth <= 0 || maxHeight <= 0 || pbm.width <= 0 || pbm.height <= 0 {
+		return
+	}
+	w, h := fitSize(pbm.width, pbm.height, maxWidth, maxHeight)
+	pbm.Resize(w, h, filter)
+}
+
+// Fill scales the PBM image, preserving its aspect ratio, until it covers
+// width×height, then crops the overflow evenly from both sides.
+func (pbm *PBM) Fill(width, height int, filter Filter) {
+	if width <= 0 || height <= 0 || pbm.width <= 0 || pbm.height <= 0 {
+		return
+	}
+	w, h := fillSize(pbm.width, pbm.height, width, height)
+	pbm.Resize(w, h, filter)
+	pbm.CanvasResize(width, height, AnchorCenter, false)
+}
@@ -0,0 +1,289 @@
+package Netpbm
+
+import "math"
+
+// Rotate90CW rotates the PBM image 90° clockwise.
+func (pbm *PBM) Rotate90CW() {
+	pbm.Transpose()
+	pbm.Flip()
+}
+
+// Rotate90CCW rotates the PBM image 90° counter-clockwise.
+func (pbm *PBM) Rotate90CCW() {
+	pbm.Transpose()
+	pbm.Flop()
+}
+
+// Rotate180 rotates the PBM image by 180°.
+func (pbm *PBM) Rotate180() {
+	pbm.Flip()
+	pbm.Flop()
+}
+
+// Transpose mirrors the PBM image across its main diagonal, so that the pixel
+// at (x, y) moves to (y, x).
+func (pbm *PBM) Transpose() {
+	pbm.data = transposeGrid(pbm.data, pbm.width, pbm.height)
+	pbm.width, pbm.height = pbm.height, pbm.width
+}
+
+// Transverse mirrors the PBM image across its anti-diagonal.
+func (pbm *PBM) Transverse() {
+	pbm.Transpose()
+	pbm.Rotate180()
+}
+
+// Rotate90CCW rotates the PGM image 90° counter-clockwise.
+func (pgm *PGM) Rotate90CCW() {
+	pgm.Transpose()
+	pgm.Flop()
+}
+
+// Rotate180 rotates the PGM image by 180°.
+func (pgm *PGM) Rotate180() {
+	pgm.Flip()
+	pgm.Flop()
+}
+
+// Transpose mirrors the PGM image across its main diagonal, so that the pixel
+// at (x, y) moves to (y, x).
+func (pgm *PGM) Transpose() {
+	pgm.data = transposeGrid(pgm.data, pgm.width, pgm.height)
+	pgm.width, pgm.height = pgm.height, pgm.width
+}
+
+// Transverse mirrors the PGM image across its anti-diagonal.
+func (pgm *PGM) Transverse() {
+	pgm.Transpose()
+	pgm.Rotate180()
+}
+
+// Rotate90CCW rotates the PPM image 90° counter-clockwise.
+func (ppm *PPM) Rotate90CCW() {
+	ppm.Transpose()
+	ppm.Flop()
+}
+
+// Rotate180 rotates the PPM image by 180°.
+func (ppm *PPM) Rotate180() {
+	ppm.Flip()
+	ppm.Flop()
+}
+
+// Transpose mirrors the PPM image across its main diagonal, so that the pixel
+// at (x, y) moves to (y, x).
+func (ppm *PPM) Transpose() {
+	ppm.data = transposeGrid(ppm.data, ppm.width, ppm.height)
+	ppm.width, ppm.height = ppm.height, ppm.width
+}
+
+// Transverse mirrors the PPM image across its anti-diagonal.
+func (ppm *PPM) Transverse() {
+	ppm.Transpose()
+	ppm.Rotate180()
+}
+
+// RotateMode selects the canvas size produced by Rotate.
+type RotateMode int
+
+const (
+	// RotateExpand grows the canvas so that the whole rotated image fits.
+	RotateExpand RotateMode = iota
+	// RotateKeepSize keeps the original canvas size, cropping the corners.
+	RotateKeepSize
+)
+
+// quarterTurns returns the number of counter-clockwise quarter turns in
+// angle, and whether angle is an exact multiple of 90°.
+func quarterTurns(angle float64) (int, bool) {
+	q := angle / 90
+	if q != math.Trunc(q) || math.IsInf(q, 0) {
+		return 0, false
+	}
+	return int(math.Mod(math.Mod(q, 4)+4, 4)), true
+}
+
+// losslessTurns reports whether a rotation can be done by moving pixels
+// around instead of resampling them, and how many quarter turns it takes.
+func losslessTurns(angle float64, mode RotateMode, width, height int) (int, bool) {
+	turns, ok := quarterTurns(angle)
+	if !ok {
+		return 0, false
+	}
+	return turns, mode == RotateExpand || turns%2 == 0 || width == height
+}
+
+// rotatedSize returns the canvas size needed to hold a width×height image
+// rotated by angle radians.
+func rotatedSize(width, height int, angle float64, mode RotateMode) (int, int) {
+	if mode == RotateKeepSize {
+		return width, height
+	}
+	sin, cos := math.Abs(math.Sin(angle)), math.Abs(math.Cos(angle))
+	w := float64(width)*cos + float64(height)*sin
+	h := float64(width)*sin + float64(height)*cos
+	return max(int(math.Ceil(w-1e-6)), 1), max(int(math.Ceil(h-1e-6)), 1)
+}
+
+// rotation returns the mapping from destination pixels of a width×height
+// canvas back to source pixels of an image rotated counter-clockwise by angle
+// radians around its center.
+func rotation(srcWidth, srcHeight, width, height int, angle float64) func(x, y float64) (float64, float64) {
+	sin, cos := math.Sin(angle), math.Cos(angle)
+	scx, scy := float64(srcWidth-1)/2, float64(srcHeight-1)/2
+	dcx, dcy := float64(width-1)/2, float64(height-1)/2
+	return func(x, y float64) (float64, float64) {
+		dx, dy := x-dcx, y-dcy
+		return cos*dx - sin*dy + scx, sin*dx + cos*dy + scy
+	}
+}
+
+// sample interpolates the plane at (x, y), where pixel centers lie on
+// integer coordinates. Pixels outside the plane take the background value.
+func (p *plane) sample(x, y float64, filter Filter, background float64) float64 {
+	if math.IsNaN(x) || math.IsNaN(y) || math.IsInf(x, 0) || math.IsInf(y, 0) {
+		return background
+	}
+	if filter == NearestNeighbor {
+		ix, iy := int(math.Floor(x+0.5)), int(math.Floor(y+0.5))
+		if ix < 0 || ix >= p.width || iy < 0 || iy >= p.height {
+			return background
+		}
+		return p.at(ix, iy)
+	}
+
+	support := int(filter.support())
+	x0, y0 := int(math.Floor(x)), int(math.Floor(y))
+	sum, total := 0.0, 0.0
+	for iy := y0 - support + 1; iy <= y0+support; iy++ {
+		wy := filter.kernel(y - float64(iy))
+		if wy == 0 {
+			continue
+		}
+		for ix := x0 - support + 1; ix <= x0+support; ix++ {
+			w := wy * filter.kernel(x-float64(ix))
+			if w == 0 {
+				continue
+			}
+			v := background
+			if ix >= 0 && ix < p.width && iy >= 0 && iy < p.height {
+				v = p.at(ix, iy)
+			}
+			sum += v * w
+			total += w
+		}
+	}
+	if total == 0 {
+		return background
+	}
+	return sum / total
+}
+
+// warp returns a width×height plane whose pixels are sampled from p at the
+// positions given by inverse, which maps destination to source coordinates.
+func (p *plane) warp(width, height int, inverse func(x, y float64) (float64, float64), filter Filter, background float64) *plane {
+	out := newPlane(width, height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			sx, sy := inverse(float64(x), float64(y))
+			out.set(x, y, p.sample(sx, sy, filter, background))
+		}
+	}
+	return out
+}
+
+// Rotate rotates the PBM image counter-clockwise by angle degrees, like
+// pnmrotate. NearestNeighbor keeps pixels crisp; other filters interpolate
+// black coverage and threshold it at one half. Uncovered areas are set to
+// background. Multiples of 90° are done losslessly.
+func (pbm *PBM) Rotate(angle float64, filter Filter, background bool, mode RotateMode) {
+	if turns, ok := losslessTurns(angle, mode, pbm.width, pbm.height); ok {
+		pbm.rotateQuarters(turns)
+		return
+	}
+	if pbm.width <= 0 || pbm.height <= 0 {
+		return
+	}
+	bg := 0.0
+	if background {
+		bg = 1
+	}
+	theta := angle * math.Pi / 180
+	width, height := rotatedSize(pbm.width, pbm.height, theta, mode)
+	inverse := rotation(pbm.width, pbm.height, width, height, theta)
+	pbm.setBitPlane(pbm.bitPlane().warp(width, height, inverse, filter, bg))
+}
+
+func (pbm *PBM) rotateQuarters(turns int) {
+	switch turns {
+	case 1:
+		pbm.Rotate90CCW()
+	case 2:
+		pbm.Rotate180()
+	case 3:
+		pbm.Rotate90CW()
+	}
+}
+
+// Rotate rotates the PGM image counter-clockwise by angle degrees, like
+// pnmrotate. Interpolation is done in linear light and uncovered areas are
+// set to background. Multiples of 90° are done losslessly.
+func (pgm *PGM) Rotate(angle float64, filter Filter, background uint8, mode RotateMode) {
+	if turns, ok := losslessTurns(angle, mode, pgm.width, pgm.height); ok {
+		pgm.rotateQuarters(turns)
+		return
+	}
+	if pgm.width <= 0 || pgm.height <= 0 {
+		return
+	}
+	bg := decodeTable(pgm.max, true)[background]
+	theta := angle * math.Pi / 180
+	width, height := rotatedSize(pgm.width, pgm.height, theta, mode)
+	inverse := rotation(pgm.width, pgm.height, width, height, theta)
+	pgm.setPlane(pgm.plane(true).warp(width, height, inverse, filter, bg), true)
+}
+
+func (pgm *PGM) rotateQuarters(turns int) {
+	switch turns {
+	case 1:
+		pgm.Rotate90CCW()
+	case 2:
+		pgm.Rotate180()
+	case 3:
+		pgm.Rotate90CW()
+	}
+}
+
+// Rotate rotates the PPM image counter-clockwise by angle degrees, like
+// pnmrotate. Interpolation is done in linear light and uncovered areas are
+// set to background. Multiples of 90° are done losslessly.
+func (ppm *PPM) Rotate(angle float64, filter Filter, background Pixel, mode RotateMode) {
+	if turns, ok := losslessTurns(angle, mode, ppm.width, ppm.height); ok {
+		ppm.rotateQuarters(turns)
+		return
+	}
+	if ppm.width <= 0 || ppm.height <= 0 {
+		return
+	}
+	table := decodeTable(ppm.max, true)
+	bg := [3]float64{table[background.R], table[background.G], table[background.B]}
+	theta := angle * math.Pi / 180
+	width, height := rotatedSize(ppm.width, ppm.height, theta, mode)
+	inverse := rotation(ppm.width, ppm.height, width, height, theta)
+	ps := ppm.planes(true)
+	for c := range ps {
+		ps[c] = ps[c].warp(width, height, inverse, filter, bg[c])
+	}
+	ppm.setPlanes(ps, true)
+}
+
+func (ppm *PPM) rotateQuarters(turns int) {
+	switch turns {
+	case 1:
+		ppm.Rotate90CCW()
+	case 2:
+		ppm.Rotate180()
+	case 3:
+		ppm.Rotate90CW()
+	}
+}
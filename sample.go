@@ -0,0 +1,145 @@
+package Netpbm
+
+import (
+	"fmt"
+	"math"
+)
+
+// This file holds the conversions between samples and levels shared by the
+// tonal operations. Samples are the integers stored in an image, in
+// [0, max]; levels are the same values normalized to [0, 1]. Every
+// conversion back to samples rounds to the nearest integer and clamps, and
+// a max value of zero is handled without dividing by it.
+
+// scaleSample rescales a sample from the range [0, from] to [0, to],
+// rounding to the nearest integer. Samples above from are treated as from.
+// With from equal to zero every sample maps to zero.
+func scaleSample(v, from, to uint8) uint8 {
+	if from == 0 {
+		return 0
+	}
+	v = min(v, from)
+	return uint8((uint(v)*uint(to) + uint(from)/2) / uint(from))
+}
+
+// scaleTable returns the 256-entry lookup table of scaleSample.
+func scaleTable(from, to uint8) []uint8 {
+	table := make([]uint8, 256)
+	for v := range table {
+		table[v] = scaleSample(uint8(v), from, to)
+	}
+	return table
+}
+
+// invertSample returns the complement of a sample in [0, max]. Samples
+// above max are treated as max.
+func invertSample(v, max uint8) uint8 {
+	return max - min(v, max)
+}
+
+// invertTable returns the 256-entry lookup table of invertSample.
+func invertTable(max uint8) []uint8 {
+	table := make([]uint8, 256)
+	for v := range table {
+		table[v] = invertSample(uint8(v), max)
+	}
+	return table
+}
+
+// srgbToLinear decodes a normalized sRGB value to linear light.
+func srgbToLinear(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// linearToSRGB encodes a normalized linear-light value with the sRGB curve.
+func linearToSRGB(v float64) float64 {
+	if v <= 0.0031308 {
+		return v * 12.92
+	}
+	return 1.055*math.Pow(v, 1/2.4) - 0.055
+}
+
+// toSample converts a normalized value to a sample in [0, max], rounding to
+// the nearest integer and clamping out-of-range values.
+func toSample(v float64, max uint8) uint8 {
+	v *= float64(max)
+	if v <= 0 {
+		return 0
+	}
+	if v >= float64(max) {
+		return max
+	}
+	return uint8(v + 0.5)
+}
+
+// decodeTable maps every sample in [0, max] to its normalized value, decoded
+// to linear light when linear is set.
+func decodeTable(max uint8, linear bool) []float64 {
+	table := make([]float64, 256)
+	if max == 0 {
+		return table
+	}
+	for v := range table {
+		table[v] = float64(v) / float64(max)
+		if linear {
+			table[v] = srgbToLinear(table[v])
+		}
+	}
+	return table
+}
+
+// encodeSample is the inverse of decodeTable for a single value.
+func encodeSample(v float64, max uint8, linear bool) uint8 {
+	if linear {
+		v = linearToSRGB(math.Max(v, 0))
+	}
+	return toSample(v, max)
+}
+
+// toneTable returns a 256-entry lookup table applying f to the normalized
+// levels of an image with the given max value. Levels above max are treated
+// as max.
+func toneTable(max uint8, f func(float64) float64) []uint8 {
+	table := make([]uint8, 256)
+	if max == 0 {
+		return table
+	}
+	for v := range table {
+		table[v] = toSample(f(math.Min(float64(v)/float64(max), 1)), max)
+	}
+	return table
+}
+
+// lutTable checks that lut holds one entry per level of an image with the
+// given max value and widens it to 256 entries, clamping entries to max.
+func lutTable(lut []uint8, max uint8) ([]uint8, error) {
+	if len(lut) != int(max)+1 {
+		return nil, fmt.Errorf("lookup table has %d entries, expected %d", len(lut), int(max)+1)
+	}
+	table := make([]uint8, 256)
+	for v := range table {
+		table[v] = min(lut[min(v, int(max))], max)
+	}
+	return table, nil
+}
+
+// mapSamples replaces every sample of the PGM image with its entry in table,
+// which must have 256 entries.
+func (pgm *PGM) mapSamples(table []uint8) {
+	for y := 0; y < pgm.height; y++ {
+		for x := 0; x < pgm.width; x++ {
+			pgm.data[y][x] = table[pgm.data[y][x]]
+		}
+	}
+}
+
+// mapChannels replaces every sample of the PPM image with its entry in the
+// table of its channel. The tables must have 256 entries.
+func (ppm *PPM) mapChannels(r, g, b []uint8) {
+	ppm.mapPixels(func(p Pixel) Pixel {
+		return Pixel{r[p.R], g[p.G], b[p.B]}
+	})
+}
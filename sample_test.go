@@ -0,0 +1,125 @@
+package Netpbm
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func randomPGM(r *rand.Rand, width, height int, max uint8) *PGM {
+	pgm := &PGM{newGrid[uint8](width, height), width, height, "P2", max}
+	for y := range pgm.data {
+		for x := range pgm.data[y] {
+			pgm.data[y][x] = uint8(r.Intn(int(max) + 1))
+		}
+	}
+	return pgm
+}
+
+func randomPPM(r *rand.Rand, width, height int, max uint8) *PPM {
+	ppm := &PPM{newGrid[Pixel](width, height), width, height, "P3", max}
+	for y := range ppm.data {
+		for x := range ppm.data[y] {
+			n := int(max) + 1
+			ppm.data[y][x] = Pixel{uint8(r.Intn(n)), uint8(r.Intn(n)), uint8(r.Intn(n))}
+		}
+	}
+	return ppm
+}
+
+func TestScaleSampleRoundTrip(t *testing.T) {
+	for from := 1; from <= 255; from++ {
+		for to := from; to <= 255; to++ {
+			for v := 0; v <= from; v++ {
+				up := scaleSample(uint8(v), uint8(from), uint8(to))
+				if back := scaleSample(up, uint8(to), uint8(from)); int(back) != v {
+					t.Fatalf("%d scaled from %d to %d and back gives %d", v, from, to, back)
+				}
+			}
+		}
+	}
+}
+
+func TestSetMaxValueRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, max := range []uint8{1, 2, 15, 100, 254} {
+		pgm := randomPGM(r, 7, 5, max)
+		want := pgm.Clone()
+		pgm.SetMaxValue(255)
+		pgm.SetMaxValue(max)
+		if !pgm.Equal(want) {
+			t.Errorf("PGM round trip through max 255 changed an image of max %d", max)
+		}
+
+		ppm := randomPPM(r, 7, 5, max)
+		wantPPM := ppm.Clone()
+		ppm.SetMaxValue(255)
+		ppm.SetMaxValue(max)
+		if !ppm.Equal(wantPPM) {
+			t.Errorf("PPM round trip through max 255 changed an image of max %d", max)
+		}
+	}
+}
+
+func TestSetMaxValueZero(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+
+	pgm := randomPGM(r, 4, 3, 255)
+	pgm.SetMaxValue(0)
+	pgm.SetMaxValue(255)
+	for y := range pgm.data {
+		for x, v := range pgm.data[y] {
+			if v != 0 {
+				t.Fatalf("PGM sample (%d, %d) is %d after going through max 0", x, y, v)
+			}
+		}
+	}
+
+	ppm := randomPPM(r, 4, 3, 255)
+	ppm.SetMaxValue(0)
+	ppm.SetMaxValue(255)
+	for y := range ppm.data {
+		for x, p := range ppm.data[y] {
+			if p != (Pixel{}) {
+				t.Fatalf("PPM pixel (%d, %d) is %v after going through max 0", x, y, p)
+			}
+		}
+	}
+}
+
+func TestInvertTwice(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	for max := 0; max <= 255; max++ {
+		pgm := randomPGM(r, 6, 4, uint8(max))
+		want := pgm.Clone()
+		pgm.Invert()
+		pgm.Invert()
+		if !pgm.Equal(want) {
+			t.Fatalf("inverting a PGM of max %d twice changed it", max)
+		}
+
+		ppm := randomPPM(r, 6, 4, uint8(max))
+		wantPPM := ppm.Clone()
+		ppm.Invert()
+		ppm.Invert()
+		if !ppm.Equal(wantPPM) {
+			t.Fatalf("inverting a PPM of max %d twice changed it", max)
+		}
+	}
+}
+
+func TestInvertStaysBelowMax(t *testing.T) {
+	for max := 0; max < 255; max++ {
+		ppm := &PPM{newGrid[Pixel](256, 1), 256, 1, "P3", uint8(max)}
+		for x := range ppm.data[0] {
+			// Samples above max can be read from files and must not
+			// produce samples above max either.
+			ppm.data[0][x] = Pixel{uint8(x), uint8(255 - x), uint8(x / 2)}
+		}
+		ppm.Invert()
+		for x, p := range ppm.data[0] {
+			if p.R > uint8(max) || p.G > uint8(max) || p.B > uint8(max) {
+				t.Fatalf("pixel %d is %v after Invert with max %d", x, p, max)
+			}
+		}
+	}
+}
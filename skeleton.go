@@ -0,0 +1,258 @@
+package Netpbm
+
+import (
+	"math"
+	"sort"
+)
+
+// ThinningMethod selects the algorithm used by Thin.
+type ThinningMethod int
+
+const (
+	// ZhangSuen is the parallel thinning algorithm of Zhang and Suen. It is
+	// fast but may leave two-pixel-thick diagonal lines.
+	ZhangSuen ThinningMethod = iota
+	// GuoHall is the parallel thinning algorithm of Guo and Hall, which gives
+	// thinner diagonals.
+	GuoHall
+)
+
+// neighborOffsets lists the 8 neighbors of a pixel clockwise from the one
+// above it, as P2 to P9 in the thinning literature.
+var neighborOffsets = [8]Point{{0, -1}, {1, -1}, {1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1}}
+
+// neighbors returns the 8 neighbors of (x, y) as a bit mask, with bit i set
+// when neighbor i of neighborOffsets is black. Pixels outside the image are
+// white.
+func (pbm *PBM) neighbors(x, y int) uint8 {
+	var mask uint8
+	for i, o := range neighborOffsets {
+		nx, ny := x+o.X, y+o.Y
+		if nx >= 0 && ny >= 0 && nx < pbm.width && ny < pbm.height && pbm.data[ny][nx] {
+			mask |= 1 << i
+		}
+	}
+	return mask
+}
+
+// bit returns neighbor i of a neighbor mask, counting from zero, as 0 or 1.
+func bit(mask uint8, i int) int {
+	return int(mask >> (i % 8) & 1)
+}
+
+// blackNeighbors returns the number of black neighbors in a neighbor mask.
+func blackNeighbors(mask uint8) int {
+	n := 0
+	for i := 0; i < 8; i++ {
+		n += bit(mask, i)
+	}
+	return n
+}
+
+// transitions returns the number of white to black transitions around the
+// neighbors of a neighbor mask.
+func transitions(mask uint8) int {
+	n := 0
+	for i := 0; i < 8; i++ {
+		if bit(mask, i) == 0 && bit(mask, i+1) == 1 {
+			n++
+		}
+	}
+	return n
+}
+
+// simple reports whether removing a black pixel with the given neighbors
+// keeps the 8-connectivity of black pixels and the 4-connectivity of white
+// ones, using the connectivity number of Yokoi.
+func simple(mask uint8) bool {
+	n := 0
+	for k := 0; k < 8; k += 2 {
+		a, b, c := 1-bit(mask, k), 1-bit(mask, k+1), 1-bit(mask, k+2)
+		n += a - a*b*c
+	}
+	return n == 1
+}
+
+// zhangSuenRemovable reports whether Zhang–Suen removes a pixel with the
+// given neighbors in the first or second subiteration.
+func zhangSuenRemovable(mask uint8, second bool) bool {
+	p := func(i int) int { return bit(mask, i-2) }
+	b := blackNeighbors(mask)
+	if b < 2 || b > 6 || transitions(mask) != 1 {
+		return false
+	}
+	if second {
+		return p(2)*p(4)*p(8) == 0 && p(2)*p(6)*p(8) == 0
+	}
+	return p(2)*p(4)*p(6) == 0 && p(4)*p(6)*p(8) == 0
+}
+
+// guoHallRemovable reports whether Guo–Hall removes a pixel with the given
+// neighbors in the first or second subiteration.
+func guoHallRemovable(mask uint8, second bool) bool {
+	p := func(i int) int { return bit(mask, i-2) }
+	not := func(v int) int { return 1 - v }
+	c := not(p(2))&(p(3)|p(4)) + not(p(4))&(p(5)|p(6)) + not(p(6))&(p(7)|p(8)) + not(p(8))&(p(9)|p(2))
+	n1 := (p(9) | p(2)) + (p(3) | p(4)) + (p(5) | p(6)) + (p(7) | p(8))
+	n2 := (p(2) | p(3)) + (p(4) | p(5)) + (p(6) | p(7)) + (p(8) | p(9))
+	if c != 1 || min(n1, n2) < 2 || min(n1, n2) > 3 {
+		return false
+	}
+	if second {
+		return (p(2)|p(3)|not(p(5)))&p(4) == 0
+	}
+	return (p(6)|p(7)|not(p(9)))&p(8) == 0
+}
+
+// Thin returns a copy of the PBM image whose black regions are thinned down
+// to one-pixel-wide lines that keep their topology, with the given method.
+func (pbm *PBM) Thin(method ThinningMethod) *PBM {
+	removable := zhangSuenRemovable
+	if method == GuoHall {
+		removable = guoHallRemovable
+	}
+	out := pbm.Clone()
+	var marked []Point
+	for changed := true; changed; {
+		changed = false
+		for _, second := range []bool{false, true} {
+			marked = marked[:0]
+			for y := 0; y < out.height; y++ {
+				for x := 0; x < out.width; x++ {
+					if out.data[y][x] && removable(out.neighbors(x, y), second) {
+						marked = append(marked, Point{x, y})
+					}
+				}
+			}
+			for _, q := range marked {
+				out.data[q.Y][q.X] = false
+			}
+			changed = changed || len(marked) > 0
+		}
+	}
+	return out
+}
+
+// borderSides lists the neighbors above, below, right and left of a pixel
+// as indices into neighborOffsets.
+var borderSides = [4]int{0, 4, 2, 6}
+
+// MedialAxis returns the medial axis of the black regions of the PBM image
+// as a new PBM image, along with the Euclidean distance from every axis
+// pixel to the nearest white pixel, zero elsewhere. The pixels are peeled one
+// distance level at a time, from the outside in. Within a level, the simple
+// pixels that are not line ends are removed in parallel from the top, bottom,
+// right and left borders in turn, as in Rosenfeld's thinning, so the axis is
+// connected, one pixel wide, follows the ridges of the distance transform and
+// does not depend on the scan order. The distance times two is the local
+// thickness.
+func (pbm *PBM) MedialAxis() (*PBM, [][]float64) {
+	sq := pbm.squaredEDT()
+	var order []Point
+	for y := 0; y < pbm.height; y++ {
+		for x := 0; x < pbm.width; x++ {
+			if pbm.data[y][x] {
+				order = append(order, Point{x, y})
+			}
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return sq[order[i].Y][order[i].X] < sq[order[j].Y][order[j].X]
+	})
+	var levels [][]Point
+	for i := 0; i < len(order); {
+		j := i + 1
+		for j < len(order) && sq[order[j].Y][order[j].X] == sq[order[i].Y][order[i].X] {
+			j++
+		}
+		levels = append(levels, order[i:j])
+		i = j
+	}
+
+	out := pbm.Clone()
+	var marked []Point
+	for changed := true; changed; {
+		changed = false
+		for _, level := range levels {
+			for peeled := true; peeled; {
+				peeled = false
+				for _, side := range borderSides {
+					marked = marked[:0]
+					for _, q := range level {
+						if !out.data[q.Y][q.X] {
+							continue
+						}
+						mask := out.neighbors(q.X, q.Y)
+						if bit(mask, side) == 0 && blackNeighbors(mask) >= 2 && simple(mask) {
+							marked = append(marked, q)
+						}
+					}
+					for _, q := range marked {
+						out.data[q.Y][q.X] = false
+					}
+					peeled = peeled || len(marked) > 0
+				}
+				changed = changed || peeled
+			}
+		}
+	}
+
+	dist := newGrid[float64](pbm.width, pbm.height)
+	for y := 0; y < pbm.height; y++ {
+		for x := 0; x < pbm.width; x++ {
+			if out.data[y][x] {
+				dist[y][x] = math.Sqrt(sq[y][x])
+			}
+		}
+	}
+	return out, dist
+}
+
+// Prune returns a copy of the PBM image, assumed to be a one-pixel-wide
+// skeleton, without its spurs: the branches of at most length pixels that
+// run from a line end to a junction. Lines that do not reach a junction are
+// kept whatever their length.
+func (pbm *PBM) Prune(length int) *PBM {
+	out := pbm.Clone()
+	inside := func(x, y int) bool {
+		return x >= 0 && y >= 0 && x < pbm.width && y < pbm.height && pbm.data[y][x]
+	}
+	for y := 0; y < pbm.height; y++ {
+		for x := 0; x < pbm.width; x++ {
+			if !pbm.data[y][x] || blackNeighbors(pbm.neighbors(x, y)) != 1 {
+				continue
+			}
+			// Follow the branch from this end until it reaches a junction,
+			// stops, or grows longer than a spur.
+			path := []Point{{x, y}}
+			visited := map[Point]bool{{x, y}: true}
+			for len(path) <= length+1 {
+				q := path[len(path)-1]
+				if len(path) > 1 && transitions(pbm.neighbors(q.X, q.Y)) >= 3 {
+					for _, p := range path[:len(path)-1] {
+						out.data[p.Y][p.X] = false
+					}
+					break
+				}
+				next, found, edge := Point{}, false, false
+				for i, o := range neighborOffsets {
+					p := Point{q.X + o.X, q.Y + o.Y}
+					if !inside(p.X, p.Y) || visited[p] {
+						continue
+					}
+					// Prefer edge neighbors so corners of the line are not
+					// skipped.
+					if !found || i%2 == 0 && !edge {
+						next, found, edge = p, true, i%2 == 0
+					}
+				}
+				if !found {
+					break
+				}
+				visited[next] = true
+				path = append(path, next)
+			}
+		}
+	}
+	return out
+}
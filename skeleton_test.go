@@ -0,0 +1,38 @@
+package Netpbm
+
+import "testing"
+
+func TestMedialAxisSymmetric(t *testing.T) {
+	for _, size := range []Point{{22, 9}, {21, 9}, {15, 8}, {9, 9}, {12, 12}, {30, 5}} {
+		pbm := &PBM{newGrid[bool](size.X+2, size.Y+2), size.X + 2, size.Y + 2, "P1"}
+		for y := 1; y <= size.Y; y++ {
+			for x := 1; x <= size.X; x++ {
+				pbm.data[y][x] = true
+			}
+		}
+		axis, _ := pbm.MedialAxis()
+
+		_, components, err := axis.ConnectedComponents(8)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(components) != 1 {
+			t.Errorf("%dx%d rectangle: axis has %d components, want 1", size.X, size.Y, len(components))
+		}
+
+		// Equal distance levels used to be peeled in raster order, which bent
+		// the axis toward the bottom right corner.
+		flipped := axis.Clone()
+		flipped.Flip()
+		if !flipped.Equal(axis) {
+			t.Errorf("%dx%d rectangle: axis is not symmetric left to right", size.X, size.Y)
+		}
+		if size.Y%2 == 1 {
+			flopped := axis.Clone()
+			flopped.Flop()
+			if !flopped.Equal(axis) {
+				t.Errorf("%dx%d rectangle: axis is not symmetric top to bottom", size.X, size.Y)
+			}
+		}
+	}
+}
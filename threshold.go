@@ -0,0 +1,234 @@
+package Netpbm
+
+import "math"
+
+// ThresholdMethod selects how Binarize chooses the threshold of each pixel.
+type ThresholdMethod int
+
+const (
+	// Otsu picks the global level that maximizes the variance between the
+	// dark and light classes.
+	Otsu ThresholdMethod = iota
+	// Triangle picks the global level farthest from the line joining the
+	// histogram peak to the end of its longest tail. It suits images with a
+	// dominant background, such as scanned pages.
+	Triangle
+	// Isodata picks the global level that lies halfway between the means of
+	// the two classes it separates (Ridler–Calvard).
+	Isodata
+	// AdaptiveMean compares each pixel with the mean of its window minus C.
+	AdaptiveMean
+	// AdaptiveGaussian compares each pixel with the Gaussian-weighted mean
+	// of its window minus C.
+	AdaptiveGaussian
+	// Sauvola compares each pixel with m·(1 + K·(s/R − 1)), where m and s are
+	// the mean and standard deviation of its window and R is half the max
+	// value.
+	Sauvola
+	// Niblack compares each pixel with m + K·s.
+	Niblack
+)
+
+// BinarizeOptions configures Binarize. The zero value selects Otsu.
+type BinarizeOptions struct {
+	Method ThresholdMethod
+	// Window is the side of the square neighborhood used by the local
+	// methods. It is rounded up to an odd number; zero selects 15.
+	Window int
+	// K weights the local standard deviation for Sauvola and Niblack.
+	// Zero selects 0.34 for Sauvola and -0.2 for Niblack.
+	K float64
+	// C is subtracted, in samples, from the local mean by AdaptiveMean and
+	// AdaptiveGaussian.
+	C float64
+}
+
+// Threshold converts the PGM image to a PBM image in which the pixels below
+// level are black.
+func (pgm *PGM) Threshold(level uint8) *PBM {
+	pbm := &PBM{newGrid[bool](pgm.width, pgm.height), pgm.width, pgm.height, "P1"}
+	for y := 0; y < pgm.height; y++ {
+		for x := 0; x < pgm.width; x++ {
+			pbm.data[y][x] = pgm.data[y][x] < level
+		}
+	}
+	return pbm
+}
+
+// OtsuLevel returns the level computed by Otsu's method, suitable for
+// Threshold.
+func (pgm *PGM) OtsuLevel() uint8 {
+	hist := pgm.Histogram()
+	total, sum := 0.0, 0.0
+	for v, n := range hist {
+		total += float64(n)
+		sum += float64(v * n)
+	}
+
+	best, bestVariance := int(pgm.max)/2+1, -1.0
+	count, partial := 0.0, 0.0
+	for t := 1; t < len(hist); t++ {
+		// Class 0 holds the levels below t.
+		count += float64(hist[t-1])
+		partial += float64((t - 1) * hist[t-1])
+		if count == 0 || count == total {
+			continue
+		}
+		m0 := partial / count
+		m1 := (sum - partial) / (total - count)
+		variance := count * (total - count) * (m0 - m1) * (m0 - m1)
+		if variance > bestVariance {
+			best, bestVariance = t, variance
+		}
+	}
+	return uint8(min(best, int(pgm.max)))
+}
+
+// TriangleLevel returns the level computed by the triangle method, suitable
+// for Threshold.
+func (pgm *PGM) TriangleLevel() uint8 {
+	hist := pgm.Histogram()
+	lo, hi, peak := -1, -1, 0
+	for v, n := range hist {
+		if n == 0 {
+			continue
+		}
+		if lo < 0 {
+			lo = v
+		}
+		hi = v
+		if n > hist[peak] {
+			peak = v
+		}
+	}
+	if lo < 0 || lo == hi {
+		return uint8(int(pgm.max)/2 + 1)
+	}
+
+	// Work on the longer tail; the distance to the line joining the peak to
+	// the tail's end is proportional to the vertical gap for a fixed line.
+	end, step := lo, -1
+	if hi-peak > peak-lo {
+		end, step = hi, 1
+	}
+	best, bestDistance := peak, -1.0
+	slope := float64(hist[peak]) / float64(peak-end)
+	for v := peak; v != end; v += step {
+		line := slope * float64(v-end)
+		if d := line - float64(hist[v]); d > bestDistance {
+			best, bestDistance = v, d
+		}
+	}
+	if step < 0 {
+		// The dark tail is the foreground: keep the chosen level in it.
+		return uint8(min(best+1, int(pgm.max)))
+	}
+	return uint8(best)
+}
+
+// IsodataLevel returns the level computed by the iterative isodata method,
+// suitable for Threshold.
+func (pgm *PGM) IsodataLevel() uint8 {
+	hist := pgm.Histogram()
+	total, sum := 0.0, 0.0
+	for v, n := range hist {
+		total += float64(n)
+		sum += float64(v * n)
+	}
+	if total == 0 {
+		return uint8(int(pgm.max)/2 + 1)
+	}
+
+	t := sum / total
+	for i := 0; i < 256; i++ {
+		var n0, s0, n1, s1 float64
+		for v, n := range hist {
+			if float64(v) <= t {
+				n0 += float64(n)
+				s0 += float64(v * n)
+			} else {
+				n1 += float64(n)
+				s1 += float64(v * n)
+			}
+		}
+		if n0 == 0 || n1 == 0 {
+			break
+		}
+		next := (s0/n0 + s1/n1) / 2
+		if math.Abs(next-t) < 0.5 {
+			t = next
+			break
+		}
+		t = next
+	}
+	return uint8(min(int(t)+1, int(pgm.max)))
+}
+
+// Binarize converts the PGM image to a PBM image using the thresholding
+// method selected in opts. Pixels below their threshold become black.
+func (pgm *PGM) Binarize(opts BinarizeOptions) *PBM {
+	switch opts.Method {
+	case Otsu:
+		return pgm.Threshold(pgm.OtsuLevel())
+	case Triangle:
+		return pgm.Threshold(pgm.TriangleLevel())
+	case Isodata:
+		return pgm.Threshold(pgm.IsodataLevel())
+	}
+
+	radius := 7
+	if opts.Window > 0 {
+		radius = opts.Window / 2
+	}
+	p := pgm.plane(false)
+	c := 0.0
+	if pgm.max > 0 {
+		c = opts.C / float64(pgm.max)
+	}
+
+	var threshold func(x, y int) float64
+	switch opts.Method {
+	case AdaptiveGaussian:
+		weights := gaussianWeights(float64(2*radius+1) / 6)
+		blurred := p.separable(weights, weights, EdgeClamp)
+		threshold = func(x, y int) float64 {
+			return blurred.at(x, y) - c
+		}
+	case Sauvola, Niblack:
+		k := opts.K
+		if k == 0 {
+			k = -0.2
+			if opts.Method == Sauvola {
+				k = 0.34
+			}
+		}
+		stats := p.summedArea()
+		threshold = func(x, y int) float64 {
+			mean, stddev := stats.stats(x, y, radius)
+			if opts.Method == Sauvola {
+				return mean * (1 + k*(stddev/0.5-1))
+			}
+			return mean + k*stddev
+		}
+	default:
+		stats := p.summedArea()
+		threshold = func(x, y int) float64 {
+			mean, _ := stats.stats(x, y, radius)
+			return mean - c
+		}
+	}
+
+	pbm := &PBM{newGrid[bool](pgm.width, pgm.height), pgm.width, pgm.height, "P1"}
+	for y := 0; y < pgm.height; y++ {
+		for x := 0; x < pgm.width; x++ {
+			pbm.data[y][x] = p.at(x, y) < threshold(x, y)
+		}
+	}
+	return pbm
+}
+
+// Binarize converts the PPM image to a PBM image by applying the
+// thresholding method selected in opts to the luma of each pixel.
+func (ppm *PPM) Binarize(opts BinarizeOptions) *PBM {
+	return ppm.luma().Binarize(opts)
+}
@@ -0,0 +1,211 @@
+package Netpbm
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// CurvePoint is a control point of a tone curve. In and Out are normalized
+// levels in [0, 1], where 1 stands for the max value of the image.
+type CurvePoint struct {
+	In, Out float64
+}
+
+// gammaCurve raises levels to 1/g, so that g above one brightens the
+// midtones like pnmgamma.
+func gammaCurve(g float64) func(float64) float64 {
+	return func(v float64) float64 {
+		if g <= 0 {
+			return v
+		}
+		return math.Pow(v, 1/g)
+	}
+}
+
+// brightnessContrastCurve adds brightness and scales the distance from
+// mid-gray by a factor that goes from zero at contrast -1 through one at 0 to
+// infinity at +1.
+func brightnessContrastCurve(brightness, contrast float64) func(float64) float64 {
+	contrast = math.Max(-1, math.Min(contrast, 1))
+	factor := math.Tan((contrast + 1) * math.Pi / 4)
+	return func(v float64) float64 {
+		return (v-0.5)*factor + 0.5 + brightness
+	}
+}
+
+// levelsCurve maps [inBlack, inWhite] to [outBlack, outWhite] with a gamma
+// correction in between, all in normalized levels.
+func levelsCurve(inBlack, inWhite, gamma, outBlack, outWhite float64) func(float64) float64 {
+	g := gammaCurve(gamma)
+	return func(v float64) float64 {
+		if inWhite <= inBlack {
+			if v < inWhite {
+				v = 0
+			} else {
+				v = 1
+			}
+		} else {
+			v = math.Max(0, math.Min((v-inBlack)/(inWhite-inBlack), 1))
+		}
+		return outBlack + g(v)*(outWhite-outBlack)
+	}
+}
+
+// splineCurve returns the monotone cubic (Fritsch–Carlson) interpolation of
+// the control points, which passes through every point without overshooting
+// between them. Levels outside the points take the value of the nearest one.
+func splineCurve(points []CurvePoint) (func(float64) float64, error) {
+	if len(points) < 2 {
+		return nil, errors.New("a curve needs at least two control points")
+	}
+	pts := append([]CurvePoint(nil), points...)
+	sort.Slice(pts, func(i, j int) bool { return pts[i].In < pts[j].In })
+	n := len(pts)
+	slopes := make([]float64, n-1)
+	for i := range slopes {
+		dx := pts[i+1].In - pts[i].In
+		if dx <= 0 {
+			return nil, errors.New("curve control points must have distinct inputs")
+		}
+		slopes[i] = (pts[i+1].Out - pts[i].Out) / dx
+	}
+
+	// Tangents start as the average of neighboring slopes and are then
+	// limited so that every segment stays monotone.
+	tangents := make([]float64, n)
+	tangents[0], tangents[n-1] = slopes[0], slopes[n-2]
+	for i := 1; i < n-1; i++ {
+		if slopes[i-1]*slopes[i] <= 0 {
+			tangents[i] = 0
+		} else {
+			tangents[i] = (slopes[i-1] + slopes[i]) / 2
+		}
+	}
+	for i, s := range slopes {
+		if s == 0 {
+			tangents[i], tangents[i+1] = 0, 0
+			continue
+		}
+		a, b := tangents[i]/s, tangents[i+1]/s
+		if h := a*a + b*b; h > 9 {
+			t := 3 / math.Sqrt(h)
+			tangents[i], tangents[i+1] = t*a*s, t*b*s
+		}
+	}
+
+	return func(v float64) float64 {
+		if v <= pts[0].In {
+			return pts[0].Out
+		}
+		if v >= pts[n-1].In {
+			return pts[n-1].Out
+		}
+		i := sort.Search(n, func(i int) bool { return pts[i].In > v }) - 1
+		h := pts[i+1].In - pts[i].In
+		t := (v - pts[i].In) / h
+		t2, t3 := t*t, t*t*t
+		return (2*t3-3*t2+1)*pts[i].Out + (t3-2*t2+t)*h*tangents[i] +
+			(-2*t3+3*t2)*pts[i+1].Out + (t3-t2)*h*tangents[i+1]
+	}, nil
+}
+
+// ApplyLUT replaces every sample v of the PGM image with lut[v]. The table
+// must have max+1 entries.
+func (pgm *PGM) ApplyLUT(lut []uint8) error {
+	table, err := lutTable(lut, pgm.max)
+	if err != nil {
+		return err
+	}
+	pgm.mapSamples(table)
+	return nil
+}
+
+// Gamma applies a gamma correction to the PGM image: levels are raised to
+// 1/g, so g above one brightens the midtones, like pnmgamma.
+func (pgm *PGM) Gamma(g float64) {
+	pgm.mapSamples(toneTable(pgm.max, gammaCurve(g)))
+}
+
+// BrightnessContrast adjusts the PGM image. brightness in [-1, 1] is added
+// as a fraction of the max value; contrast in [-1, 1] stretches (positive)
+// or compresses (negative) levels around mid-gray.
+func (pgm *PGM) BrightnessContrast(brightness, contrast float64) {
+	pgm.mapSamples(toneTable(pgm.max, brightnessContrastCurve(brightness, contrast)))
+}
+
+// Levels maps the range [inBlack, inWhite] of the PGM image to
+// [outBlack, outWhite], applying gamma to the midtones in between. Levels
+// are samples relative to the max value of the image.
+func (pgm *PGM) Levels(inBlack, inWhite uint8, gamma float64, outBlack, outWhite uint8) {
+	m := math.Max(float64(pgm.max), 1)
+	curve := levelsCurve(float64(inBlack)/m, float64(inWhite)/m, gamma, float64(outBlack)/m, float64(outWhite)/m)
+	pgm.mapSamples(toneTable(pgm.max, curve))
+}
+
+// Curves applies the smooth monotone tone curve passing through the given
+// control points to the PGM image.
+func (pgm *PGM) Curves(points []CurvePoint) error {
+	curve, err := splineCurve(points)
+	if err != nil {
+		return err
+	}
+	pgm.mapSamples(toneTable(pgm.max, curve))
+	return nil
+}
+
+// ApplyLUT replaces every sample v of the PPM image with lut[v] in all three
+// channels. The table must have max+1 entries.
+func (ppm *PPM) ApplyLUT(lut []uint8) error {
+	return ppm.ApplyChannelLUTs(lut, lut, lut)
+}
+
+// ApplyChannelLUTs applies a separate lookup table to each channel of the
+// PPM image. Every table must have max+1 entries.
+func (ppm *PPM) ApplyChannelLUTs(r, g, b []uint8) error {
+	var tables [3][]uint8
+	for c, lut := range [][]uint8{r, g, b} {
+		table, err := lutTable(lut, ppm.max)
+		if err != nil {
+			return err
+		}
+		tables[c] = table
+	}
+	ppm.mapChannels(tables[0], tables[1], tables[2])
+	return nil
+}
+
+// Gamma applies a gamma correction to every channel of the PPM image:
+// levels are raised to 1/g, so g above one brightens the midtones.
+func (ppm *PPM) Gamma(g float64) {
+	table := toneTable(ppm.max, gammaCurve(g))
+	ppm.mapChannels(table, table, table)
+}
+
+// BrightnessContrast adjusts every channel of the PPM image with the same
+// parameters as PGM.BrightnessContrast.
+func (ppm *PPM) BrightnessContrast(brightness, contrast float64) {
+	table := toneTable(ppm.max, brightnessContrastCurve(brightness, contrast))
+	ppm.mapChannels(table, table, table)
+}
+
+// Levels maps the range [inBlack, inWhite] of every channel of the PPM image
+// to [outBlack, outWhite], applying gamma to the midtones in between.
+func (ppm *PPM) Levels(inBlack, inWhite uint8, gamma float64, outBlack, outWhite uint8) {
+	m := math.Max(float64(ppm.max), 1)
+	curve := levelsCurve(float64(inBlack)/m, float64(inWhite)/m, gamma, float64(outBlack)/m, float64(outWhite)/m)
+	table := toneTable(ppm.max, curve)
+	ppm.mapChannels(table, table, table)
+}
+
+// Curves applies the smooth monotone tone curve passing through the given
+// control points to every channel of the PPM image.
+func (ppm *PPM) Curves(points []CurvePoint) error {
+	curve, err := splineCurve(points)
+	if err != nil {
+		return err
+	}
+	table := toneTable(ppm.max, curve)
+	ppm.mapChannels(table, table, table)
+	return nil
+}
@@ -0,0 +1,215 @@
+package Netpbm
+
+import (
+	"errors"
+	"math"
+)
+
+// Affine is a 2D affine transform holding the first two rows of a 3×3
+// matrix, so that a point (x, y) maps to
+//
+//	x' = a[0]*x + a[1]*y + a[2]
+//	y' = a[3]*x + a[4]*y + a[5]
+//
+// Coordinates are in pixels with the y axis pointing down and pixel centers
+// on integer coordinates.
+type Affine [6]float64
+
+// IdentityAffine returns the transform that leaves every point in place.
+func IdentityAffine() Affine {
+	return Affine{1, 0, 0, 0, 1, 0}
+}
+
+// Apply maps the point (x, y) through the transform.
+func (a Affine) Apply(x, y float64) (float64, float64) {
+	return a[0]*x + a[1]*y + a[2], a[3]*x + a[4]*y + a[5]
+}
+
+// Compose returns the transform that applies a first and then b.
+func (a Affine) Compose(b Affine) Affine {
+	return Affine{
+		b[0]*a[0] + b[1]*a[3], b[0]*a[1] + b[1]*a[4], b[0]*a[2] + b[1]*a[5] + b[2],
+		b[3]*a[0] + b[4]*a[3], b[3]*a[1] + b[4]*a[4], b[3]*a[2] + b[4]*a[5] + b[5],
+	}
+}
+
+// Translate returns a followed by a translation by (tx, ty).
+func (a Affine) Translate(tx, ty float64) Affine {
+	return a.Compose(Affine{1, 0, tx, 0, 1, ty})
+}
+
+// Scale returns a followed by a scaling by sx and sy about the origin.
+func (a Affine) Scale(sx, sy float64) Affine {
+	return a.Compose(Affine{sx, 0, 0, 0, sy, 0})
+}
+
+// Rotate returns a followed by a counter-clockwise rotation by angle degrees
+// about the origin, matching the direction used by the Rotate methods.
+func (a Affine) Rotate(angle float64) Affine {
+	sin, cos := math.Sincos(angle * math.Pi / 180)
+	return a.Compose(Affine{cos, sin, 0, -sin, cos, 0})
+}
+
+// RotateAround returns a followed by a counter-clockwise rotation by angle
+// degrees about the point (cx, cy).
+func (a Affine) RotateAround(angle, cx, cy float64) Affine {
+	return a.Translate(-cx, -cy).Rotate(angle).Translate(cx, cy)
+}
+
+// Shear returns a followed by a shear that moves x by shx*y and y by shy*x.
+func (a Affine) Shear(shx, shy float64) Affine {
+	return a.Compose(Affine{1, shx, 0, shy, 1, 0})
+}
+
+// Invert returns the inverse transform, or an error if a is singular.
+func (a Affine) Invert() (Affine, error) {
+	det := a[0]*a[4] - a[1]*a[3]
+	if math.Abs(det) < 1e-12 {
+		return Affine{}, errors.New("affine transform is not invertible")
+	}
+	return Affine{
+		a[4] / det, -a[1] / det, (a[1]*a[5] - a[4]*a[2]) / det,
+		-a[3] / det, a[0] / det, (a[3]*a[2] - a[0]*a[5]) / det,
+	}, nil
+}
+
+// Homography returns the transform as a 3×3 perspective matrix.
+func (a Affine) Homography() Homography {
+	return Homography{a[0], a[1], a[2], a[3], a[4], a[5], 0, 0, 1}
+}
+
+// Homography is a 3×3 perspective transform stored in row-major order, so
+// that a point (x, y) maps to
+//
+//	w  = h[6]*x + h[7]*y + h[8]
+//	x' = (h[0]*x + h[1]*y + h[2]) / w
+//	y' = (h[3]*x + h[4]*y + h[5]) / w
+type Homography [9]float64
+
+// Apply maps the point (x, y) through the transform. Points sent to
+// infinity come back as NaN.
+func (h Homography) Apply(x, y float64) (float64, float64) {
+	w := h[6]*x + h[7]*y + h[8]
+	if w == 0 {
+		return math.NaN(), math.NaN()
+	}
+	return (h[0]*x + h[1]*y + h[2]) / w, (h[3]*x + h[4]*y + h[5]) / w
+}
+
+// Compose returns the transform that applies h first and then g.
+func (h Homography) Compose(g Homography) Homography {
+	var m Homography
+	for r := 0; r < 3; r++ {
+		for c := 0; c < 3; c++ {
+			m[r*3+c] = g[r*3]*h[c] + g[r*3+1]*h[3+c] + g[r*3+2]*h[6+c]
+		}
+	}
+	return m
+}
+
+// Invert returns the inverse transform, or an error if h is singular.
+func (h Homography) Invert() (Homography, error) {
+	inv := Homography{
+		h[4]*h[8] - h[5]*h[7], h[2]*h[7] - h[1]*h[8], h[1]*h[5] - h[2]*h[4],
+		h[5]*h[6] - h[3]*h[8], h[0]*h[8] - h[2]*h[6], h[2]*h[3] - h[0]*h[5],
+		h[3]*h[7] - h[4]*h[6], h[1]*h[6] - h[0]*h[7], h[0]*h[4] - h[1]*h[3],
+	}
+	det := h[0]*inv[0] + h[1]*inv[3] + h[2]*inv[6]
+	if math.Abs(det) < 1e-12 {
+		return Homography{}, errors.New("perspective transform is not invertible")
+	}
+	for i := range inv {
+		inv[i] /= det
+	}
+	return inv, nil
+}
+
+// HomographyFromPoints returns the perspective transform that maps each of
+// the four src points onto the matching dst point. It fails when three of
+// the points are collinear.
+func HomographyFromPoints(src, dst [4]Point) (Homography, error) {
+	// Each correspondence gives two linear equations in the eight unknown
+	// matrix entries, with h[8] fixed to 1.
+	var m [8][9]float64
+	for i := 0; i < 4; i++ {
+		x, y := float64(src[i].X), float64(src[i].Y)
+		u, v := float64(dst[i].X), float64(dst[i].Y)
+		m[2*i] = [9]float64{x, y, 1, 0, 0, 0, -u * x, -u * y, u}
+		m[2*i+1] = [9]float64{0, 0, 0, x, y, 1, -v * x, -v * y, v}
+	}
+
+	// Gaussian elimination with partial pivoting.
+	for col := 0; col < 8; col++ {
+		pivot := col
+		for r := col + 1; r < 8; r++ {
+			if math.Abs(m[r][col]) > math.Abs(m[pivot][col]) {
+				pivot = r
+			}
+		}
+		if math.Abs(m[pivot][col]) < 1e-9 {
+			return Homography{}, errors.New("points do not define a perspective transform")
+		}
+		m[col], m[pivot] = m[pivot], m[col]
+		for r := 0; r < 8; r++ {
+			if r == col {
+				continue
+			}
+			f := m[r][col] / m[col][col]
+			for c := col; c < 9; c++ {
+				m[r][c] -= f * m[col][c]
+			}
+		}
+	}
+
+	var h Homography
+	for i := 0; i < 8; i++ {
+		h[i] = m[i][8] / m[i][i]
+	}
+	h[8] = 1
+	return h, nil
+}
+
+// Warp maps the PGM image through the affine transform m, keeping the canvas
+// size. Interpolation is done in linear light with the given filter, and
+// areas not covered by the source are set to background.
+func (pgm *PGM) Warp(m Affine, filter Filter, background uint8) error {
+	return pgm.WarpPerspective(m.Homography(), filter, background)
+}
+
+// WarpPerspective maps the PGM image through the perspective transform h,
+// keeping the canvas size. Interpolation is done in linear light with the
+// given filter, and areas not covered by the source are set to background.
+func (pgm *PGM) WarpPerspective(h Homography, filter Filter, background uint8) error {
+	inv, err := h.Invert()
+	if err != nil {
+		return err
+	}
+	bg := decodeTable(pgm.max, true)[background]
+	pgm.setPlane(pgm.plane(true).warp(pgm.width, pgm.height, inv.Apply, filter, bg), true)
+	return nil
+}
+
+// Warp maps the PPM image through the affine transform m, keeping the canvas
+// size. Interpolation is done in linear light with the given filter, and
+// areas not covered by the source are set to background.
+func (ppm *PPM) Warp(m Affine, filter Filter, background Pixel) error {
+	return ppm.WarpPerspective(m.Homography(), filter, background)
+}
+
+// WarpPerspective maps the PPM image through the perspective transform h,
+// keeping the canvas size. Interpolation is done in linear light with the
+// given filter, and areas not covered by the source are set to background.
+func (ppm *PPM) WarpPerspective(h Homography, filter Filter, background Pixel) error {
+	inv, err := h.Invert()
+	if err != nil {
+		return err
+	}
+	table := decodeTable(ppm.max, true)
+	bg := [3]float64{table[background.R], table[background.G], table[background.B]}
+	ps := ppm.planes(true)
+	for c := range ps {
+		ps[c] = ps[c].warp(ppm.width, ppm.height, inv.Apply, filter, bg[c])
+	}
+	ppm.setPlanes(ps, true)
+	return nil
+}